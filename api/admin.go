@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/project-n-oss/sidekick/app"
+)
+
+// CreateAdminHandler creates the http.Handler for the sidekick admin api.
+// The admin api is meant to be served on a separate listener from the proxied traffic, so that
+// its routes can never collide with a bucket name.
+func (api *Api) CreateAdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	api.handleHealthChecks(mux)
+	mux.Handle("/metrics", api.app.Metrics().Handler())
+	mux.HandleFunc("/config", api.handleConfig)
+	mux.HandleFunc("/upstream/clients", api.handleUpstreamClients)
+	mux.Handle("/loglevel", api.app.LogLevel())
+	mux.HandleFunc("/loglevel/overrides", api.handleLogOverrides)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/pprof/capture", api.handleProfileCapture)
+	mux.HandleFunc("/debug/pprof/rates", api.handleProfileRates)
+
+	mux.HandleFunc("/runtime", api.handleRuntime)
+	mux.HandleFunc("/runtime/gc", api.handleRuntimeGC)
+	mux.HandleFunc("/runtime/free-os-memory", api.handleRuntimeFreeOSMemory)
+
+	return mux
+}
+
+// CreateHealthHandler creates the http.Handler serving only the health and readiness checks.
+// Unlike the admin api, it exposes nothing sensitive and can listen on the pod network.
+func (api *Api) CreateHealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	api.handleHealthChecks(mux)
+	return mux
+}
+
+func (api *Api) handleHealthChecks(mux *http.ServeMux) {
+	mux.HandleFunc("/health", api.handleHealth)
+	mux.HandleFunc("/ready", api.handleReady)
+}
+
+// handleHealth is the liveness check, it fails only if sidekick should be restarted.
+func (api *Api) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if err := api.app.Live(r.Context()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReady is the readiness check, it reports the details of every check as json.
+func (api *Api) handleReady(w http.ResponseWriter, r *http.Request) {
+	report := api.app.Ready(r.Context())
+	statusCode := http.StatusOK
+	if !report.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	writeJSON(w, statusCode, report)
+}
+
+func (api *Api) handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Api Config
+		App app.Config
+	}{
+		Api: api.Config(),
+		App: api.app.Config(),
+	})
+}
+
+// handleUpstreamClients reports the aws credentials and clients cached by the app.
+func (api *Api) handleUpstreamClients(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.app.AwsRegistry().Stats())
+}
+
+type runtimeStats struct {
+	GoVersion    string
+	NumCPU       int
+	GOMAXPROCS   int
+	NumGoroutine int
+	HeapAlloc    uint64
+	HeapSys      uint64
+	NumGC        uint32
+}
+
+func (api *Api) handleRuntime(w http.ResponseWriter, r *http.Request) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	writeJSON(w, http.StatusOK, runtimeStats{
+		GoVersion:    runtime.Version(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumGoroutine: runtime.NumGoroutine(),
+		HeapAlloc:    memStats.HeapAlloc,
+		HeapSys:      memStats.HeapSys,
+		NumGC:        memStats.NumGC,
+	})
+}
+
+func (api *Api) handleRuntimeGC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	runtime.GC()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *Api) handleRuntimeFreeOSMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	debug.FreeOSMemory()
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	sidekickLogger "github.com/project-n-oss/sidekick/pkg/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultLogOverrideTtl is how long a log override lasts when no Ttl is given.
+const defaultLogOverrideTtl = 15 * time.Minute
+
+type logOverrideRequest struct {
+	Bucket    string
+	UserAgent string
+	Level     string
+	Ttl       string
+}
+
+func (r logOverrideRequest) kindAndValue() (sidekickLogger.OverrideKind, string, error) {
+	switch {
+	case r.Bucket != "" && r.UserAgent != "":
+		return "", "", fmt.Errorf("only one of Bucket or UserAgent can be set")
+	case r.Bucket != "":
+		return sidekickLogger.BucketOverride, r.Bucket, nil
+	case r.UserAgent != "":
+		return sidekickLogger.UserAgentOverride, r.UserAgent, nil
+	default:
+		return "", "", fmt.Errorf("one of Bucket or UserAgent must be set")
+	}
+}
+
+type logOverrideResponse struct {
+	Kind      sidekickLogger.OverrideKind
+	Value     string
+	Level     string
+	ExpiresAt time.Time
+}
+
+// handleLogOverrides lists (GET), adds (POST) and removes (DELETE) temporary log level overrides.
+func (api *Api) handleLogOverrides(w http.ResponseWriter, r *http.Request) {
+	overrides := api.app.LogOverrides()
+
+	switch r.Method {
+	case http.MethodGet:
+		ret := []logOverrideResponse{}
+		for _, override := range overrides.List() {
+			ret = append(ret, newLogOverrideResponse(override))
+		}
+		writeJSON(w, http.StatusOK, ret)
+
+	case http.MethodPost:
+		var req logOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid log override: %v", err), http.StatusBadRequest)
+			return
+		}
+		kind, value, err := req.kindAndValue()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		level := zapcore.DebugLevel
+		if req.Level != "" {
+			if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ttl := defaultLogOverrideTtl
+		if req.Ttl != "" {
+			if ttl, err = time.ParseDuration(req.Ttl); err != nil || ttl <= 0 {
+				http.Error(w, fmt.Sprintf("invalid Ttl %q", req.Ttl), http.StatusBadRequest)
+				return
+			}
+		}
+
+		override := overrides.Set(kind, value, level, ttl)
+		writeJSON(w, http.StatusOK, newLogOverrideResponse(override))
+
+	case http.MethodDelete:
+		req := logOverrideRequest{
+			Bucket:    r.URL.Query().Get("bucket"),
+			UserAgent: r.URL.Query().Get("useragent"),
+		}
+		kind, value, err := req.kindAndValue()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		overrides.Delete(kind, value)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newLogOverrideResponse(override sidekickLogger.Override) logOverrideResponse {
+	return logOverrideResponse{
+		Kind:      override.Kind,
+		Value:     override.Value,
+		Level:     override.Level.String(),
+		ExpiresAt: override.ExpiresAt,
+	}
+}
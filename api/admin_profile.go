@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/project-n-oss/sidekick/pkg/profile"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCaptureSeconds = 30
+	maxCaptureSeconds     = 600
+)
+
+// handleProfileCapture captures a cpu profile for ?seconds=N (30 by default) and returns it as default.pgo.
+// The profile is also saved in the configured ProfileDirectory.
+func (api *Api) handleProfileCapture(w http.ResponseWriter, r *http.Request) {
+	seconds := defaultCaptureSeconds
+	if v := r.URL.Query().Get("seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxCaptureSeconds {
+			http.Error(w, fmt.Sprintf("seconds must be between 1 and %d", maxCaptureSeconds), http.StatusBadRequest)
+			return
+		}
+		seconds = n
+	}
+
+	// the profile is only written once the capture is done, so errors can still be reported with a status code
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="default.pgo"`)
+	path, err := api.profiler.CaptureCPU(r.Context(), time.Duration(seconds)*time.Second, w)
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		if errors.Is(err, profile.ErrCaptureInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		api.app.Logger().Error("cpu profile capture failed", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if path != "" {
+		api.app.Logger().Info("cpu profile captured", zap.String("path", path), zap.Int("seconds", seconds))
+	}
+}
+
+type profileRates struct {
+	MutexProfileFraction *int
+	BlockProfileRate     *int
+}
+
+// handleProfileRates returns (GET) or changes (PUT) the mutex and block profile rates.
+func (api *Api) handleProfileRates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var rates profileRates
+		if err := json.NewDecoder(r.Body).Decode(&rates); err != nil {
+			http.Error(w, fmt.Sprintf("invalid profile rates: %v", err), http.StatusBadRequest)
+			return
+		}
+		if rates.MutexProfileFraction != nil {
+			runtime.SetMutexProfileFraction(*rates.MutexProfileFraction)
+		}
+		if rates.BlockProfileRate != nil {
+			runtime.SetBlockProfileRate(*rates.BlockProfileRate)
+			api.blockProfileRate.Store(int64(*rates.BlockProfileRate))
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// a negative value reads the current rate without changing it
+	mutexProfileFraction := runtime.SetMutexProfileFraction(-1)
+	blockProfileRate := int(api.blockProfileRate.Load())
+	writeJSON(w, http.StatusOK, profileRates{
+		MutexProfileFraction: &mutexProfileFraction,
+		BlockProfileRate:     &blockProfileRate,
+	})
+}
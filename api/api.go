@@ -2,29 +2,104 @@ package api
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httputil"
+	"runtime"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/project-n-oss/sidekick/app"
+	"github.com/project-n-oss/sidekick/pkg/metrics"
+	"github.com/project-n-oss/sidekick/pkg/profile"
 	"go.uber.org/zap"
 )
 
 type Api struct {
-	app *app.App
+	cfg      atomic.Pointer[Config]
+	app      *app.App
+	profiler *profile.Profiler
+	// blockProfileRate is the current block profile rate, the runtime does not expose it.
+	blockProfileRate atomic.Int64
+
+	// inFlight is the number of proxied requests being served
+	inFlight atomic.Int64
+	// closed is set once Close is called, requests are then rejected
+	closed atomic.Bool
+
+	requestsTotal    *metrics.Counter
+	requestDuration  *metrics.Histogram
+	requestsInFlight *metrics.Gauge
+	panicsTotal      *metrics.Counter
+	phaseDuration    *metrics.Histogram
 }
 
+// ErrClosed is returned for requests served after Close.
+var ErrClosed = errors.New("sidekick is closed")
+
 func New(ctx context.Context, cfg Config, app *app.App) (*Api, error) {
-	return &Api{
-		app: app,
-	}, nil
+	// profile rates are process wide, leave them alone unless configured
+	if cfg.MutexProfileFraction != 0 {
+		runtime.SetMutexProfileFraction(cfg.MutexProfileFraction)
+	}
+	if cfg.BlockProfileRate != 0 {
+		runtime.SetBlockProfileRate(cfg.BlockProfileRate)
+	}
+
+	registry := app.Metrics()
+	ret := &Api{
+		app:      app,
+		profiler: profile.NewProfiler(cfg.ProfileDirectory),
+
+		requestsTotal:    registry.Counter("sidekick_requests_total", "Total number of proxied requests.", "method", "code"),
+		requestDuration:  registry.Histogram("sidekick_request_duration_seconds", "Duration of proxied requests.", metrics.DefaultBuckets, "method"),
+		requestsInFlight: registry.Gauge("sidekick_requests_in_flight", "Number of proxied requests being served."),
+		panicsTotal:      registry.Counter("sidekick_panics_total", "Total number of panics recovered while serving requests."),
+		phaseDuration:    registry.Histogram("sidekick_request_phase_duration_seconds", "Duration of the phases of proxied requests: credential lookup, dns, connect, tls handshake, time to first byte, crunch head and body transfer.", metrics.DefaultBuckets, "phase"),
+	}
+	ret.cfg.Store(&cfg)
+	ret.blockProfileRate.Store(int64(cfg.BlockProfileRate))
+	return ret, nil
+}
+
+// Config returns the configuration the api is running with.
+func (api *Api) Config() Config {
+	return *api.cfg.Load()
+}
+
+// Reload swaps cfg atomically with the current configuration and applies the changed profile rates.
+func (api *Api) Reload(cfg Config) error {
+	current := api.cfg.Swap(&cfg)
+	if cfg.MutexProfileFraction != current.MutexProfileFraction {
+		runtime.SetMutexProfileFraction(cfg.MutexProfileFraction)
+	}
+	if cfg.BlockProfileRate != current.BlockProfileRate {
+		runtime.SetBlockProfileRate(cfg.BlockProfileRate)
+		api.blockProfileRate.Store(int64(cfg.BlockProfileRate))
+	}
+	return nil
 }
 
-// CreateHandler creates the http.Handler for the sidekick api
+// Close stops the api, flushing any profile capture in progress.
+// Requests served after Close fail with ErrClosed.
+func (api *Api) Close(ctx context.Context) error {
+	api.closed.Store(true)
+	api.profiler.Stop()
+	return nil
+}
+
+// InFlight returns the number of proxied requests being served.
+func (api *Api) InFlight() int64 {
+	return api.inFlight.Load()
+}
+
+// CreateHandler creates the http.Handler for the sidekick api.
+// It only serves proxied traffic, see CreateAdminHandler for health, metrics etc.
 func (api *Api) CreateHandler() http.Handler {
 	handler := http.HandlerFunc(api.routeBase)
-	handler = api.healthMiddleware(handler, api.app.Health)
+	handler = api.recoveryMiddleware(handler)
 	handler = api.sessionMiddleware(handler)
 
 	return handler
@@ -32,13 +107,18 @@ func (api *Api) CreateHandler() http.Handler {
 
 func (api *Api) routeBase(w http.ResponseWriter, req *http.Request) {
 	sess := CtxSession(req.Context())
+	if api.closed.Load() {
+		api.Error(sess, w, req, &app.Error{Kind: app.UnavailableErrorKind, Err: ErrClosed})
+		return
+	}
+
 	resp, crunched, err := sess.DoRequest(req)
 	if sess.Logger().Level() == zap.DebugLevel {
 		dumpRequest(sess.Logger(), req)
 	}
 
 	if err != nil {
-		api.InternalError(sess.Logger(), w, err)
+		api.Error(sess, w, req, err)
 		return
 	}
 	sess.WithLogger(sess.Logger().With(
@@ -60,8 +140,13 @@ func (api *Api) routeBase(w http.ResponseWriter, req *http.Request) {
 	}
 
 	w.WriteHeader(resp.StatusCode)
-	if _, err := io.Copy(w, resp.Body); err != nil {
-		api.InternalError(sess.Logger(), w, err)
+	defer resp.Body.Close()
+	bodyBegin := time.Now()
+	_, err = io.Copy(w, resp.Body)
+	sess.Timings().Set(func(t *app.Timings) { t.BodyTransfer = time.Since(bodyBegin) })
+	// the status code has already been sent, the error can only be logged
+	if err != nil {
+		sess.Logger().Error("copying upstream response body", zap.Error(err))
 		return
 	}
 }
@@ -1,4 +1,25 @@
 package api
 
 type Config struct {
+	// AdminAddress is the address the admin api (health, metrics, pprof...) listens on.
+	// It is bound to loopback by default, an empty value disables the admin api.
+	AdminAddress string `yaml:"AdminAddress" reload:"restart"`
+	// HealthAddress is the address of a listener serving only the health and readiness checks, e.g. for
+	// kubernetes probes, so that the admin api can stay on loopback. An empty value disables it.
+	HealthAddress string `yaml:"HealthAddress" reload:"restart"`
+
+	// ProfileDirectory is where cpu profiles captured from the admin api are saved.
+	// An empty value only returns captured profiles to the caller.
+	ProfileDirectory string `yaml:"ProfileDirectory" reload:"restart"`
+	// MutexProfileFraction enables the mutex profile, see runtime.SetMutexProfileFraction.
+	MutexProfileFraction int `yaml:"MutexProfileFraction"`
+	// BlockProfileRate enables the block profile, see runtime.SetBlockProfileRate.
+	BlockProfileRate int `yaml:"BlockProfileRate"`
+
+	// ShutdownGracePeriodSeconds bounds the duration of the whole graceful shutdown.
+	ShutdownGracePeriodSeconds int `yaml:"ShutdownGracePeriodSeconds"`
+	// PreStopDelaySeconds is how long sidekick keeps serving requests after it stops advertising readiness.
+	PreStopDelaySeconds int `yaml:"PreStopDelaySeconds"`
+	// DrainTimeoutSeconds is how long in-flight requests have to complete before they are cancelled.
+	DrainTimeoutSeconds int `yaml:"DrainTimeoutSeconds"`
 }
@@ -1,17 +1,120 @@
 package api
 
 import (
+	"encoding/xml"
+	"errors"
 	"net/http"
 
+	"github.com/project-n-oss/sidekick/app"
 	"go.uber.org/zap"
 )
 
-func (a *Api) InternalError(logger *zap.Logger, w http.ResponseWriter, err error) {
-	logger.Error("internal error", zap.Error(err))
-	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+// S3 error codes, see https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html#ErrorCodeList
+// Errors originating from sidekick itself use codes prefixed with Sidekick, so that clients can match on them.
+const (
+	InternalErrorCode      = "InternalError"
+	InvalidRequestCode     = "InvalidRequest"
+	AccessDeniedCode       = "AccessDenied"
+	ServiceUnavailableCode = "ServiceUnavailable"
+
+	CrunchLockedCode             = "SidekickCrunchLocked"
+	BucketExtractionFailedCode   = "SidekickBucketExtractionFailed"
+	RequestSigningFailedCode     = "SidekickRequestSigningFailed"
+	UpstreamTransportErrorCode   = "SidekickUpstreamTransportError"
+	UpstreamTimeoutCode          = "SidekickUpstreamTimeout"
+	UnsupportedCloudPlatformCode = "SidekickUnsupportedCloudPlatform"
+)
+
+// errorKinds maps the kind of errors returned by the app to http status codes and default S3 error codes.
+var errorKinds = map[app.ErrorKind]struct {
+	code       string
+	statusCode int
+}{
+	app.InternalErrorKind:            {code: InternalErrorCode, statusCode: http.StatusInternalServerError},
+	app.ClientErrorKind:              {code: InvalidRequestCode, statusCode: http.StatusBadRequest},
+	app.AuthErrorKind:                {code: AccessDeniedCode, statusCode: http.StatusForbidden},
+	app.UpstreamUnavailableErrorKind: {code: UpstreamTransportErrorCode, statusCode: http.StatusBadGateway},
+	app.UnavailableErrorKind:         {code: ServiceUnavailableCode, statusCode: http.StatusServiceUnavailable},
+	app.TimeoutErrorKind:             {code: UpstreamTimeoutCode, statusCode: http.StatusGatewayTimeout},
+}
+
+// errorCodes maps errors returned by the app to S3 error codes.
+// A non zero statusCode overrides the status code of the error kind.
+var errorCodes = []struct {
+	err        error
+	code       string
+	statusCode int
+}{
+	{err: app.ErrCrunchLocked, code: CrunchLockedCode, statusCode: http.StatusConflict},
+	{err: app.ErrAccessDenied, code: AccessDeniedCode},
+	{err: app.ErrReadOnly, code: AccessDeniedCode},
+	{err: app.ErrSourceBucket, code: BucketExtractionFailedCode},
+	{err: app.ErrSignRequest, code: RequestSigningFailedCode},
+	{err: app.ErrUpstreamTransport, code: UpstreamTransportErrorCode},
+	{err: app.ErrUpstreamTimeout, code: UpstreamTimeoutCode},
+	{err: app.ErrUnsupportedCloudPlatform, code: UnsupportedCloudPlatformCode},
+}
+
+// s3Error is the body of an S3 error response.
+type s3Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	RequestId string   `xml:"RequestId"`
+	Resource  string   `xml:"Resource"`
 }
 
-func (a *Api) BadRequest(logger *zap.Logger, w http.ResponseWriter, err error) {
-	logger.Error("bad request", zap.Error(err))
-	http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+// writeS3Error writes an S3 XML error response, so that S3 SDKs can parse the error code.
+func writeS3Error(w http.ResponseWriter, statusCode int, code, message, requestId, resource string) {
+	body, _ := xml.Marshal(s3Error{
+		Code:      code,
+		Message:   message,
+		RequestId: requestId,
+		Resource:  resource,
+	})
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+// Error writes the S3 error response matching an error returned by the app.
+// The status code is derived from the kind of the error, errors without a kind are internal errors.
+func (a *Api) Error(sess *app.Session, w http.ResponseWriter, req *http.Request, err error) {
+	kind := app.KindOf(err)
+	if kind == app.InternalErrorKind {
+		a.InternalError(sess, w, req, err)
+		return
+	}
+
+	code, statusCode := errorKinds[kind].code, errorKinds[kind].statusCode
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			code = errorCode.code
+			if errorCode.statusCode != 0 {
+				statusCode = errorCode.statusCode
+			}
+			break
+		}
+	}
+
+	sess.Logger().Warn("request error", zap.Stringer("errorKind", kind), zap.String("errorCode", code), zap.Error(err))
+	writeS3Error(w, statusCode, code, err.Error(), sess.RequestId(), req.URL.Path)
+}
+
+// InternalError writes an S3 InternalError response. Internal errors are bugs or misconfigurations of sidekick,
+// they are the only errors reported with a 500 status code.
+func (a *Api) InternalError(sess *app.Session, w http.ResponseWriter, req *http.Request, err error) {
+	code := InternalErrorCode
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			code = errorCode.code
+			break
+		}
+	}
+
+	sess.Logger().Error("internal error", zap.String("errorCode", code), zap.Error(err))
+	writeS3Error(w, http.StatusInternalServerError, code, err.Error(), sess.RequestId(), req.URL.Path)
 }
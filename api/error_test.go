@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-n-oss/sidekick/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestApi_Error(t *testing.T) {
+	testCases := []struct {
+		err        error
+		statusCode int
+		code       string
+	}{
+		{err: &app.Error{Kind: app.ClientErrorKind, Err: app.ErrCrunchLocked}, statusCode: http.StatusConflict, code: CrunchLockedCode},
+		{err: &app.Error{Kind: app.ClientErrorKind, Err: fmt.Errorf("%w: no bucket", app.ErrSourceBucket)}, statusCode: http.StatusBadRequest, code: BucketExtractionFailedCode},
+		{err: &app.Error{Kind: app.AuthErrorKind, Err: fmt.Errorf("%w: no auth header", app.ErrSourceBucket)}, statusCode: http.StatusForbidden, code: BucketExtractionFailedCode},
+		{err: &app.Error{Kind: app.AuthErrorKind, Err: fmt.Errorf("%w: GetObject my-bucket/my-key no policy rule allows it", app.ErrAccessDenied)}, statusCode: http.StatusForbidden, code: AccessDeniedCode},
+		{err: &app.Error{Kind: app.AuthErrorKind, Err: fmt.Errorf("%w: PutObject is not allowed on my-bucket", app.ErrReadOnly)}, statusCode: http.StatusForbidden, code: AccessDeniedCode},
+		{err: &app.Error{Kind: app.UpstreamUnavailableErrorKind, Err: fmt.Errorf("%w: connection refused", app.ErrUpstreamTransport)}, statusCode: http.StatusBadGateway, code: UpstreamTransportErrorCode},
+		{err: &app.Error{Kind: app.UnavailableErrorKind, Err: fmt.Errorf("%w: no credentials", app.ErrSignRequest)}, statusCode: http.StatusServiceUnavailable, code: RequestSigningFailedCode},
+		{err: &app.Error{Kind: app.TimeoutErrorKind, Err: fmt.Errorf("%w: deadline exceeded", app.ErrUpstreamTimeout)}, statusCode: http.StatusGatewayTimeout, code: UpstreamTimeoutCode},
+		{err: fmt.Errorf("%w: FOO", app.ErrUnsupportedCloudPlatform), statusCode: http.StatusInternalServerError, code: UnsupportedCloudPlatformCode},
+		{err: errors.New("unknown"), statusCode: http.StatusInternalServerError, code: InternalErrorCode},
+	}
+
+	api := &Api{}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%d_%s", tc.statusCode, tc.code), func(t *testing.T) {
+			session := (&app.App{}).NewSession().WithLogger(zap.NewNop()).WithRequestId("my-request-id")
+			req := httptest.NewRequest(http.MethodGet, "/my-bucket/my-key", nil)
+			rec := httptest.NewRecorder()
+			api.Error(session, rec, req, tc.err)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+			assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+			var body s3Error
+			require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tc.code, body.Code)
+			assert.Equal(t, tc.err.Error(), body.Message)
+			assert.Equal(t, "my-request-id", body.RequestId)
+			assert.Equal(t, "/my-bucket/my-key", body.Resource)
+		})
+	}
+}
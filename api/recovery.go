@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// recoveryMiddleware recovers panics from handler, logs them through the session logger and returns an
+// S3 InternalError, so that a single malformed request does not take down the connection.
+func (api *Api) recoveryMiddleware(handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			sess := CtxSession(r.Context())
+			api.panicsTotal.Inc()
+			sess.Logger().Error("recovered panic",
+				zap.String("panic", fmt.Sprint(rec)),
+				zap.String("stack", string(debug.Stack())),
+			)
+
+			// the response has already started, abort it so the client does not see a truncated body as complete
+			if recorder, ok := w.(*statusCodeRecorder); ok && recorder.StatusCode != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			writeS3Error(w, http.StatusInternalServerError, InternalErrorCode, "We encountered an internal error. Please try again.", sess.RequestId(), r.URL.Path)
+		}()
+
+		handler.ServeHTTP(w, r)
+	}
+}
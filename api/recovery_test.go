@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-n-oss/sidekick/app"
+	"github.com/project-n-oss/sidekick/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestApi_RecoveryMiddleware(t *testing.T) {
+	api := &Api{
+		panicsTotal: metrics.NewRegistry().Counter("panics_total", "panics"),
+	}
+	handler := api.recoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	session := (&app.App{}).NewSession().WithLogger(zap.NewNop()).WithRequestId("my-request-id")
+	req := httptest.NewRequest(http.MethodGet, "/my-bucket/my-key", nil)
+	req = req.WithContext(context.WithValue(req.Context(), sessionContextKey, session))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>InternalError</Code><Message>We encountered an internal error. Please try again.</Message><RequestId>my-request-id</RequestId><Resource>/my-bucket/my-key</Resource></Error>`, rec.Body.String())
+	assert.Equal(t, float64(1), api.panicsTotal.Value())
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+)
+
+const (
+	// RequestIdHeader carries the sidekick request id, it is read from requests and set on responses.
+	RequestIdHeader = "X-Request-Id"
+	// TraceparentHeader is the W3C trace context header, its trace id is used as request id.
+	TraceparentHeader = "Traceparent"
+
+	maxRequestIdLength = 128
+)
+
+var (
+	requestIdRegexp   = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]+$`)
+	traceparentRegexp = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// requestIdFromHeaders returns the request id sent by the client in the X-Request-Id or traceparent headers.
+// Ids that are too long or contain unexpected characters are ignored, so that they can safely be logged and echoed.
+func requestIdFromHeaders(header http.Header) (string, bool) {
+	if id := header.Get(RequestIdHeader); id != "" && len(id) <= maxRequestIdLength && requestIdRegexp.MatchString(id) {
+		return id, true
+	}
+
+	if matches := traceparentRegexp.FindStringSubmatch(header.Get(TraceparentHeader)); len(matches) == 2 {
+		// an all zero trace id is invalid
+		if matches[1] != "00000000000000000000000000000000" {
+			return matches[1], true
+		}
+	}
+
+	return "", false
+}
+
+// newRequestId returns a random request id.
+func newRequestId() (string, error) {
+	id := make([]byte, 20)
+	_, err := rand.Read(id)
+	return base64.RawURLEncoding.EncodeToString(id), err
+}
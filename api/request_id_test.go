@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApi_RequestIdFromHeaders(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   http.Header
+		expected string
+	}{
+		{name: "none", header: http.Header{}},
+		{name: "request id", header: http.Header{"X-Request-Id": {"spark-task-42"}}, expected: "spark-task-42"},
+		{name: "request id over traceparent", header: http.Header{"X-Request-Id": {"spark-task-42"}, "Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}, expected: "spark-task-42"},
+		{name: "traceparent", header: http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}, expected: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "invalid traceparent", header: http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}}},
+		{name: "invalid request id", header: http.Header{"X-Request-Id": {"foo\nbar"}}},
+		{name: "request id too long", header: http.Header{"X-Request-Id": {strings.Repeat("a", 129)}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requestId, ok := requestIdFromHeaders(tc.header)
+			assert.Equal(t, tc.expected != "", ok)
+			assert.Equal(t, tc.expected, requestId)
+		})
+	}
+}
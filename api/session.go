@@ -2,13 +2,13 @@ package api
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
 	"time"
 
 	"github.com/project-n-oss/sidekick/app"
+	sidekickLogger "github.com/project-n-oss/sidekick/pkg/logger"
 	"go.uber.org/zap"
 )
 
@@ -31,20 +31,50 @@ func (r *statusCodeRecorder) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+// knownMethods are the http methods used as metric labels, others are counted as "OTHER" so that clients
+// cannot create an unbounded number of series.
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPut:     true,
+	http.MethodPost:    true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodPatch:   true,
+}
+
+// methodLabel returns the metric label of method.
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
+
 func (api *Api) sessionMiddleware(handler http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		beginTime := time.Now()
 		session := api.app.NewSession()
+		api.inFlight.Add(1)
+		api.requestsInFlight.Add(1)
+		defer func() {
+			api.inFlight.Add(-1)
+			api.requestsInFlight.Add(-1)
+		}()
 
-		id := make([]byte, 20)
-		if _, err := rand.Read(id); err != nil {
-			session.Logger().Error("failed to generate random request id, setting default")
+		requestId, ok := requestIdFromHeaders(r.Header)
+		if !ok {
+			var err error
+			if requestId, err = newRequestId(); err != nil {
+				session.Logger().Error("failed to generate random request id, setting default")
+			}
 		}
-		requestId := base64.RawURLEncoding.EncodeToString(id)
-		session = session.WithLogger(session.Logger().With(
+		w.Header().Set(RequestIdHeader, requestId)
+		session = session.WithRequestId(requestId).WithLogger(session.Logger().With(
 			zap.String("request_id", requestId),
 			zap.String("user_agent", r.UserAgent()),
 		))
+		session = session.WithLogOverride(sidekickLogger.UserAgentOverride, r.UserAgent())
 
 		hijacker, _ := w.(http.Hijacker)
 		w = &statusCodeRecorder{
@@ -69,7 +99,14 @@ func (api *Api) sessionMiddleware(handler http.Handler) http.HandlerFunc {
 				zap.String("path", path),
 				zap.Int("statusCode", statusCode),
 			)
-			logger.Info(method + " " + path)
+			logger.Info(method+" "+path, session.Timings().Fields()...)
+			api.requestsTotal.Inc(methodLabel(method), strconv.Itoa(statusCode))
+			api.requestDuration.Observe(duration.Seconds(), methodLabel(method))
+			for phase, phaseDuration := range session.Timings().Phases() {
+				if phaseDuration > 0 {
+					api.phaseDuration.Observe(phaseDuration.Seconds(), phase)
+				}
+			}
 			if session.Logger().Level() == zap.DebugLevel {
 				dump, err := httputil.DumpRequest(r, true)
 				if err != nil {
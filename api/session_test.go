@@ -0,0 +1,15 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApi_MethodLabel(t *testing.T) {
+	assert.Equal(t, http.MethodGet, methodLabel(http.MethodGet))
+	assert.Equal(t, http.MethodDelete, methodLabel(http.MethodDelete))
+	assert.Equal(t, "OTHER", methodLabel("FOO"))
+	assert.Equal(t, "OTHER", methodLabel("get"))
+}
@@ -3,9 +3,13 @@ package app
 import (
 	"context"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	sidekickLogger "github.com/project-n-oss/sidekick/pkg/logger"
+	"github.com/project-n-oss/sidekick/pkg/metrics"
 
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
@@ -13,32 +17,75 @@ import (
 )
 
 type App struct {
-	cfg    Config
-	logger *zap.Logger
+	cfg          atomic.Pointer[Config]
+	logger       *zap.Logger
+	logLevel     zap.AtomicLevel
+	logOverrides *sidekickLogger.Overrides
+	metrics      *metrics.Registry
+	awsRegistry  *sidekickAws.Registry
 
-	standardHttpClient *http.Client
+	policyDenials      *metrics.Counter
+	readOnlyRejections *metrics.Counter
+
+	upstreamHttpClient *http.Client
 	gcpHttpClient      *http.Client
+	gcpTokenSource     oauth2.TokenSource
+}
+
+type Options struct {
+	awsCredentials     aws.CredentialsProvider
+	upstreamHttpClient *http.Client
+}
+
+// WithAwsCredentials signs upstream requests with provider instead of the default aws credential chain.
+// Buckets with their own Credentials in the config are not affected.
+func WithAwsCredentials(provider aws.CredentialsProvider) func(*Options) {
+	return func(o *Options) {
+		o.awsCredentials = provider
+	}
+}
+
+// WithUpstreamHttpClient sends upstream requests with client, http.DefaultClient by default.
+func WithUpstreamHttpClient(client *http.Client) func(*Options) {
+	return func(o *Options) {
+		o.upstreamHttpClient = client
+	}
 }
 
-func New(ctx context.Context, logger *zap.Logger, cfg Config) (*App, error) {
+// New creates a new App. logLevel is the level of logger, it can be changed at runtime.
+func New(ctx context.Context, logger *zap.Logger, logLevel zap.AtomicLevel, cfg Config, opts ...func(*Options)) (*App, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
-
-	standardHttpClient := http.Client{
-		Timeout: time.Duration(90) * time.Second,
+	options := Options{
+		upstreamHttpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	registryOpts := []func(*sidekickAws.RegistryOptions){}
+	if options.awsCredentials != nil {
+		registryOpts = append(registryOpts, sidekickAws.WithDefaultCredentials(options.awsCredentials))
+	}
+	if options.upstreamHttpClient != http.DefaultClient {
+		registryOpts = append(registryOpts, sidekickAws.WithHTTPClient(options.upstreamHttpClient))
 	}
 
 	ret := &App{
-		cfg:                cfg,
 		logger:             logger,
-		standardHttpClient: &standardHttpClient,
+		logLevel:           logLevel,
+		logOverrides:       sidekickLogger.NewOverrides(),
+		metrics:            metrics.NewRegistry(),
+		awsRegistry:        sidekickAws.NewRegistry(registryOpts...),
+		upstreamHttpClient: options.upstreamHttpClient,
 	}
+	ret.cfg.Store(&cfg)
+	ret.policyDenials = ret.metrics.Counter("sidekick_policy_denials_total", "Total number of requests denied by the policy, or that would have been in dry run.", "dry_run")
+	ret.readOnlyRejections = ret.metrics.Counter("sidekick_read_only_rejections_total", "Total number of requests rejected because their bucket is read-only.", "operation")
 
 	switch cfg.CloudPlatform {
 	case AwsCloudPlatform.String():
-		sidekickAws.RefreshCredentialsPeriodically(ctx, logger)
-		sidekickAws.RefreshS3ClientPeriodically(ctx, logger)
+		ret.awsRegistry.RefreshPeriodically(ctx, logger)
 
 	case GcpCloudPlatform.String():
 		creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
@@ -46,6 +93,7 @@ func New(ctx context.Context, logger *zap.Logger, cfg Config) (*App, error) {
 			return nil, err
 		}
 		ts := oauth2.TokenSource(creds.TokenSource)
+		ret.gcpTokenSource = ts
 		ret.gcpHttpClient = &http.Client{
 			Timeout: time.Duration(90) * time.Second,
 			Transport: &oauth2.Transport{
@@ -63,6 +111,53 @@ func (a *App) Close(ctx context.Context) error {
 	return nil
 }
 
-func (a *App) Health(ctx context.Context) error {
+// Config returns the configuration the app is running with.
+func (a *App) Config() Config {
+	if cfg := a.cfg.Load(); cfg != nil {
+		return *cfg
+	}
+	return Config{}
+}
+
+// Reload validates cfg and swaps it atomically with the current configuration.
+// Sessions created before the reload keep the configuration they started with.
+func (a *App) Reload(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	a.cfg.Store(&cfg)
 	return nil
 }
+
+// upstreamClient returns the http client upstream requests are sent with.
+func (a *App) upstreamClient() *http.Client {
+	if a.upstreamHttpClient == nil {
+		return http.DefaultClient
+	}
+	return a.upstreamHttpClient
+}
+
+// AwsRegistry returns the aws configs, credentials and s3 clients of the app.
+func (a *App) AwsRegistry() *sidekickAws.Registry {
+	return a.awsRegistry
+}
+
+// Logger returns the root logger of the app.
+func (a *App) Logger() *zap.Logger {
+	return a.logger
+}
+
+// LogLevel returns the global log level of the app.
+func (a *App) LogLevel() zap.AtomicLevel {
+	return a.logLevel
+}
+
+// LogOverrides returns the temporary per bucket and per user agent log levels.
+func (a *App) LogOverrides() *sidekickLogger.Overrides {
+	return a.logOverrides
+}
+
+// Metrics returns the registry every sidekick metric is recorded in.
+func (a *App) Metrics() *metrics.Registry {
+	return a.metrics
+}
@@ -2,8 +2,10 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
@@ -11,8 +13,13 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrCredentials is returned when upstream credentials cannot be retrieved.
+var ErrCredentials = errors.New("could not get aws credentials")
+
 type Options struct {
-	path *string
+	path                *string
+	credentialsDuration *time.Duration
+	credentialsSource   CredentialsSource
 }
 
 func WithPath(path string) func(*Options) {
@@ -21,8 +28,40 @@ func WithPath(path string) func(*Options) {
 	}
 }
 
-// NewRequest creates a standard aws s3 request
-func NewRequest(ctx context.Context, logger *zap.Logger, req *http.Request, sourceBucket SourceBucket, opts ...func(*Options)) (*http.Request, error) {
+// WithCredentialsDuration records the time spent looking up credentials in d.
+func WithCredentialsDuration(d *time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.credentialsDuration = d
+	}
+}
+
+// WithCredentialsSource signs the request with the credentials of source instead of the default credential chain.
+func WithCredentialsSource(source CredentialsSource) func(*Options) {
+	return func(o *Options) {
+		o.credentialsSource = source
+	}
+}
+
+// regionalHost returns the host of the s3 endpoint of region.
+func regionalHost(region string) string {
+	return fmt.Sprintf("s3.%s.amazonaws.com", region)
+}
+
+// defaultRegion is the region checked when none is configured in the environment, it hosts the global s3 endpoint.
+const defaultRegion = "us-east-1"
+
+// EnvRegion returns the aws region set in the environment, or us-east-1.
+func EnvRegion() string {
+	for _, key := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(key); region != "" {
+			return region
+		}
+	}
+	return defaultRegion
+}
+
+// NewRequest creates a standard aws s3 request, signed with credentials from the registry.
+func (r *Registry) NewRequest(ctx context.Context, logger *zap.Logger, req *http.Request, sourceBucket SourceBucket, opts ...func(*Options)) (*http.Request, error) {
 	options := &Options{
 		path: &req.URL.Path,
 	}
@@ -30,18 +69,22 @@ func NewRequest(ctx context.Context, logger *zap.Logger, req *http.Request, sour
 		opt(options)
 	}
 
-	awsCred, err := getCredentialsFromRegion(ctx, sourceBucket.Region)
+	credentialsBegin := time.Now()
+	awsCred, err := r.Credentials(ctx, options.credentialsSource, sourceBucket.Region)
+	if options.credentialsDuration != nil {
+		*options.credentialsDuration = time.Since(credentialsBegin)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("could not get aws credentials: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrCredentials, err)
 	}
 
 	var host string
 	switch sourceBucket.Style {
 	case VirtualHostedStyle:
-		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", sourceBucket.Bucket, sourceBucket.Region)
+		host = sourceBucket.Bucket + "." + regionalHost(sourceBucket.Region)
 	// default to path style
 	default:
-		host = fmt.Sprintf("s3.%s.amazonaws.com", sourceBucket.Region)
+		host = regionalHost(sourceBucket.Region)
 	}
 
 	clone := req.Clone(ctx)
@@ -1,73 +1,58 @@
 package aws
 
 import (
-	"context"
-	"fmt"
-	"sync"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"go.uber.org/zap"
 )
 
-// awsCredentialsMap is used to cache aws credentials for a given region.
-var awsCredentialsMap = sync.Map{}
+const (
+	// credentialsExpiryWindow is how long before they expire credentials are refreshed.
+	credentialsExpiryWindow = 5 * time.Minute
+	// credentialsRefreshInterval is how often credentials close to expiry are refreshed in the background,
+	// so that requests rarely wait for a refresh.
+	credentialsRefreshInterval = time.Minute
+)
 
-// GetAwsCredentialsFromRegion returns the aws credentials for the given region.
-func getCredentialsFromRegion(ctx context.Context, region string) (aws.Credentials, error) {
-	if awsCred, ok := awsCredentialsMap.Load(region); ok {
-		return awsCred.(aws.Credentials), nil
-	}
-	return newCredentialsFromRegion(ctx, region)
+func credentialsCacheOptions(o *aws.CredentialsCacheOptions) {
+	o.ExpiryWindow = credentialsExpiryWindow
+	// spread the refreshes of credentials that expire at the same time
+	o.ExpiryWindowJitterFrac = 0.5
 }
 
-// newAwsCredentialsFromRegion creates a new aws credentials from the given region.
-func newCredentialsFromRegion(ctx context.Context, region string) (aws.Credentials, error) {
-	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
-	if err != nil {
-		return aws.Credentials{}, err
-	}
-
-	cred, err := awsConfig.Credentials.Retrieve(ctx)
-	if err != nil {
-		return aws.Credentials{}, fmt.Errorf("could not retrieve aws credentials: %w", err)
-	}
-
-	awsCredentialsMap.Store(awsConfig.Region, cred)
-	return cred, nil
+// expiredTokenCodes are the s3 error codes returned when request credentials expired.
+var expiredTokenCodes = map[string]bool{
+	"ExpiredToken":         true,
+	"TokenRefreshRequired": true,
 }
 
-func refreshAWSCredentials(ctx context.Context, logger *zap.Logger) {
-	awsCredentialsMap.Range(func(key, value interface{}) bool {
-		region := key.(string)
-		cred := value.(aws.Credentials)
+// maxErrorBodySize bounds the part of error bodies read to find their code, s3 error documents are small.
+const maxErrorBodySize = 4 << 10
 
-		if cred.CanExpire {
-			// if credential can expire, get new credentials for the region
-			refreshedCreds, err := newCredentialsFromRegion(ctx, region)
-			if err != nil {
-				logger.Error(fmt.Sprintf("aws credential refresh failed for region %s", region), zap.Error(err))
-				return true
-			}
-			awsCredentialsMap.Store(region, refreshedCreds)
-		}
-		return true
-	})
-}
+// IsExpiredToken returns true if resp is an s3 error caused by expired credentials.
+// The start of the body of error responses is read and put back, so that the body can still be forwarded.
+func IsExpiredToken(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusForbidden {
+		return false
+	}
 
-func RefreshCredentialsPeriodically(ctx context.Context, logger *zap.Logger) {
-	refreshAWSCredentials(ctx, logger)
-	ticker := time.NewTicker(30 * time.Minute)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				ticker.Stop()
-				return
-			case <-ticker.C:
-				refreshAWSCredentials(ctx, logger)
-			}
-		}
-	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+	if err != nil {
+		return false
+	}
+	var s3Error struct {
+		Code string `xml:"Code"`
+	}
+	if err := xml.Unmarshal(body, &s3Error); err != nil {
+		return false
+	}
+	return expiredTokenCodes[s3Error.Code]
 }
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// credentialProcessTimeout bounds the duration of a credential process.
+const credentialProcessTimeout = time.Minute
+
+// ProcessProvider retrieves credentials by running an external command, e.g. a helper reading them from a vault.
+// The command prints the credentials as json on stdout, either in the aws credential_process format:
+//
+//	{"Version": 1, "AccessKeyId": "...", "SecretAccessKey": "...", "SessionToken": "...", "Expiration": "2023-10-10T10:10:10Z"}
+//
+// or in the generic format:
+//
+//	{"access_key_id": "...", "secret_access_key": "...", "session_token": "...", "expiration": "2023-10-10T10:10:10Z"}
+//
+// The session token and the expiration are optional, credentials without expiration never expire.
+// Wrap the provider in an aws.CredentialsCache to only run the command when credentials expire.
+type ProcessProvider struct {
+	command string
+}
+
+// NewProcessProvider returns a provider running command with sh.
+func NewProcessProvider(command string) *ProcessProvider {
+	return &ProcessProvider{command: command}
+}
+
+// processCredentials decodes both supported formats.
+type processCredentials struct {
+	Version         int    `json:"Version"`
+	AccessKeyId     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken"`
+	Expiration      string `json:"Expiration"`
+
+	GenericAccessKeyId     string `json:"access_key_id"`
+	GenericSecretAccessKey string `json:"secret_access_key"`
+	GenericSessionToken    string `json:"session_token"`
+	GenericExpiration      string `json:"expiration"`
+}
+
+func (p *ProcessProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialProcessTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return aws.Credentials{}, fmt.Errorf("credential process failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var decoded processCredentials
+	if err := json.Unmarshal(stdout.Bytes(), &decoded); err != nil {
+		// stdout contains secrets, it must not end up in the error
+		return aws.Credentials{}, fmt.Errorf("credential process printed invalid json")
+	}
+
+	if decoded.Version == 0 && decoded.AccessKeyId == "" {
+		decoded.AccessKeyId = decoded.GenericAccessKeyId
+		decoded.SecretAccessKey = decoded.GenericSecretAccessKey
+		decoded.SessionToken = decoded.GenericSessionToken
+		decoded.Expiration = decoded.GenericExpiration
+	} else if decoded.Version != 1 {
+		return aws.Credentials{}, fmt.Errorf("credential process printed unsupported version %d", decoded.Version)
+	}
+	if decoded.AccessKeyId == "" || decoded.SecretAccessKey == "" {
+		return aws.Credentials{}, fmt.Errorf("credential process printed no access key id or secret access key")
+	}
+
+	ret := aws.Credentials{
+		AccessKeyID:     decoded.AccessKeyId,
+		SecretAccessKey: decoded.SecretAccessKey,
+		SessionToken:    decoded.SessionToken,
+		Source:          "ProcessProvider",
+	}
+	if decoded.Expiration != "" {
+		expires, err := time.Parse(time.RFC3339, decoded.Expiration)
+		if err != nil {
+			return aws.Credentials{}, fmt.Errorf("credential process printed invalid expiration: %w", err)
+		}
+		ret.CanExpire = true
+		ret.Expires = expires
+	}
+	return ret, nil
+}
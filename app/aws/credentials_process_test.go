@@ -0,0 +1,103 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCredentialProcess writes a script printing output and counting its runs in the returned file.
+func writeCredentialProcess(t *testing.T, output string) (string, string) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := filepath.Join(dir, "credentials.sh")
+	content := fmt.Sprintf("#!/bin/sh\necho run >> %s\ncat <<'EOF'\n%s\nEOF\n", runs, output)
+	require.NoError(t, os.WriteFile(script, []byte(content), 0o755))
+	return script, runs
+}
+
+func countRuns(t *testing.T, runs string) int {
+	content, err := os.ReadFile(runs)
+	require.NoError(t, err)
+	return strings.Count(string(content), "run")
+}
+
+func TestAws_ProcessProvider(t *testing.T) {
+	ctx := context.Background()
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	for name, tc := range map[string]struct {
+		output   string
+		expected string
+		err      bool
+	}{
+		"CredentialProcess": {
+			output:   fmt.Sprintf(`{"Version": 1, "AccessKeyId": "process-key", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": %q}`, expiration.Format(time.RFC3339)),
+			expected: "process-key",
+		},
+		"Generic": {
+			output:   fmt.Sprintf(`{"access_key_id": "generic-key", "secret_access_key": "secret", "expiration": %q}`, expiration.Format(time.RFC3339)),
+			expected: "generic-key",
+		},
+		"UnsupportedVersion": {
+			output: `{"Version": 2, "AccessKeyId": "key", "SecretAccessKey": "secret"}`,
+			err:    true,
+		},
+		"MissingSecret": {
+			output: `{"access_key_id": "key"}`,
+			err:    true,
+		},
+		"InvalidJson": {
+			output: `not json`,
+			err:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			script, _ := writeCredentialProcess(t, tc.output)
+			credentials, err := NewProcessProvider(script).Retrieve(ctx)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, credentials.AccessKeyID)
+			assert.Equal(t, "secret", credentials.SecretAccessKey)
+			assert.True(t, credentials.CanExpire)
+			assert.True(t, expiration.Equal(credentials.Expires))
+		})
+	}
+
+	t.Run("Failure", func(t *testing.T) {
+		_, err := NewProcessProvider("echo denied >&2; exit 1").Retrieve(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "denied")
+	})
+}
+
+func TestAws_CredentialProcessCache(t *testing.T) {
+	ctx := context.Background()
+	expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	script, runs := writeCredentialProcess(t, fmt.Sprintf(`{"Version": 1, "AccessKeyId": "process-key", "SecretAccessKey": "secret", "Expiration": %q}`, expiration))
+
+	source := CredentialsSource{CredentialProcess: script}
+	registry := NewRegistry()
+	for i := 0; i < 3; i++ {
+		credentials, err := registry.Credentials(ctx, source, "us-east-1")
+		require.NoError(t, err)
+		assert.Equal(t, "process-key", credentials.AccessKeyID)
+	}
+	// credentials are cached until they expire
+	assert.Equal(t, 1, countRuns(t, runs))
+
+	registry.Invalidate(source)
+	_, err := registry.Credentials(ctx, source, "us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, countRuns(t, runs))
+}
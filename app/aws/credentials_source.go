@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// CredentialsSource describes where upstream credentials are loaded from.
+// The zero value is the default credential chain.
+type CredentialsSource struct {
+	// Profile is a named profile of the shared aws config and credentials files.
+	Profile string
+	// AccessKeyId, SecretAccessKey and SessionToken are static credentials.
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	// CredentialProcess is a command printing credentials as json, see ProcessProvider.
+	CredentialProcess string
+	// RoleArn is a role assumed with the credentials above, or with WebIdentityTokenFile if it is set.
+	RoleArn         string
+	ExternalId      string
+	RoleSessionName string
+	// WebIdentityTokenFile is an OIDC token file exchanged for RoleArn credentials.
+	WebIdentityTokenFile string
+}
+
+// DefaultCredentialsSource is the default credential chain.
+var DefaultCredentialsSource = CredentialsSource{}
+
+// Key identifies the source in caches. It does not contain secrets.
+func (s CredentialsSource) Key() string {
+	if s == DefaultCredentialsSource {
+		return "default"
+	}
+	h := sha256.New()
+	for _, field := range []string{s.Profile, s.AccessKeyId, s.SecretAccessKey, s.SessionToken, s.CredentialProcess, s.RoleArn, s.ExternalId, s.RoleSessionName, s.WebIdentityTokenFile} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// String describes the source for logs, without secrets.
+func (s CredentialsSource) String() string {
+	switch {
+	case s.WebIdentityTokenFile != "":
+		return "web-identity:" + s.RoleArn
+	case s.RoleArn != "":
+		return "assume-role:" + s.RoleArn
+	case s.AccessKeyId != "":
+		return "static:" + redact(s.AccessKeyId)
+	case s.CredentialProcess != "":
+		return "process"
+	case s.Profile != "":
+		return "profile:" + s.Profile
+	default:
+		return "default"
+	}
+}
+
+// redactedPrefixLen is the part of access key ids kept in logs, it only tells the kind of key, e.g. AKIA or ASIA.
+const redactedPrefixLen = 4
+
+// redact hides all but the prefix of an access key id, the access key id may come from a secret.
+func redact(accessKeyId string) string {
+	if len(accessKeyId) <= 2*redactedPrefixLen {
+		return "****"
+	}
+	return accessKeyId[:redactedPrefixLen] + "****"
+}
+
+// loadConfig loads the aws config of region, with the credentials of source.
+func (s CredentialsSource) loadConfig(ctx context.Context, region string) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		config.WithCredentialsCacheOptions(credentialsCacheOptions),
+	}
+	if s.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(s.Profile))
+	}
+	if s.AccessKeyId != "" {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.AccessKeyId, s.SecretAccessKey, s.SessionToken)))
+	}
+	if s.CredentialProcess != "" {
+		opts = append(opts, config.WithCredentialsProvider(NewProcessProvider(s.CredentialProcess)))
+	}
+	awsConfig, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	switch {
+	case s.WebIdentityTokenFile != "":
+		awsConfig.Credentials = stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(awsConfig), s.RoleArn, stscreds.IdentityTokenFile(s.WebIdentityTokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = s.RoleSessionName
+		})
+	case s.RoleArn != "":
+		awsConfig.Credentials = stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsConfig), s.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			if s.ExternalId != "" {
+				o.ExternalID = aws.String(s.ExternalId)
+			}
+			if s.RoleSessionName != "" {
+				o.RoleSessionName = s.RoleSessionName
+			}
+		})
+	}
+	return awsConfig, nil
+}
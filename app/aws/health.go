@@ -0,0 +1,37 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CheckCredentials returns an error if valid aws credentials cannot be retrieved from source for region.
+func (r *Registry) CheckCredentials(ctx context.Context, source CredentialsSource, region string) error {
+	cred, err := r.Credentials(ctx, source, region)
+	if err != nil {
+		return err
+	}
+	if cred.Expired() {
+		return fmt.Errorf("aws credentials from %s for region %s expired at %s", source, region, cred.Expires)
+	}
+	return nil
+}
+
+// CheckUpstream returns an error if the s3 endpoint of region cannot be reached with client, the client
+// upstream requests are sent with. It sends an unsigned HEAD request, any http response means the endpoint is reachable.
+func CheckUpstream(ctx context.Context, client *http.Client, region string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+regionalHost(region)+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 endpoint for region %s unreachable: %w", region, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("s3 endpoint for region %s returned %s", region, resp.Status)
+	}
+	return nil
+}
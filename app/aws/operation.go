@@ -0,0 +1,176 @@
+package aws
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CopySourceHeader is the header of CopyObject and UploadPartCopy requests naming the copied object.
+const CopySourceHeader = "X-Amz-Copy-Source"
+
+// operations maps requests to S3 api operations, see https://docs.aws.amazon.com/AmazonS3/latest/API/API_Operations_Amazon_Simple_Storage_Service.html
+// The first entry matching the method, whether the request targets an object and the subresource query parameter
+// is used, entries without subresource match requests without other query parameters than plainParameters.
+var operations = []struct {
+	method      string
+	object      bool
+	subresource string
+	operation   string
+}{
+	{method: http.MethodGet, subresource: "list-type", operation: "ListObjectsV2"},
+	{method: http.MethodGet, subresource: "versions", operation: "ListObjectVersions"},
+	{method: http.MethodGet, subresource: "uploads", operation: "ListMultipartUploads"},
+	{method: http.MethodGet, subresource: "location", operation: "GetBucketLocation"},
+	{method: http.MethodGet, subresource: "acl", operation: "GetBucketAcl"},
+	{method: http.MethodGet, subresource: "tagging", operation: "GetBucketTagging"},
+	{method: http.MethodGet, subresource: "policy", operation: "GetBucketPolicy"},
+	{method: http.MethodGet, subresource: "versioning", operation: "GetBucketVersioning"},
+	{method: http.MethodGet, subresource: "cors", operation: "GetBucketCors"},
+	{method: http.MethodGet, subresource: "lifecycle", operation: "GetBucketLifecycleConfiguration"},
+	{method: http.MethodGet, subresource: "policyStatus", operation: "GetBucketPolicyStatus"},
+	{method: http.MethodGet, subresource: "encryption", operation: "GetBucketEncryption"},
+	{method: http.MethodGet, subresource: "website", operation: "GetBucketWebsite"},
+	{method: http.MethodGet, subresource: "logging", operation: "GetBucketLogging"},
+	{method: http.MethodGet, subresource: "notification", operation: "GetBucketNotificationConfiguration"},
+	{method: http.MethodGet, subresource: "replication", operation: "GetBucketReplication"},
+	{method: http.MethodGet, subresource: "requestPayment", operation: "GetBucketRequestPayment"},
+	{method: http.MethodGet, subresource: "accelerate", operation: "GetBucketAccelerateConfiguration"},
+	{method: http.MethodGet, subresource: "ownershipControls", operation: "GetBucketOwnershipControls"},
+	{method: http.MethodGet, subresource: "publicAccessBlock", operation: "GetPublicAccessBlock"},
+	{method: http.MethodGet, subresource: "object-lock", operation: "GetObjectLockConfiguration"},
+	{method: http.MethodGet, operation: "ListObjects"},
+	{method: http.MethodHead, operation: "HeadBucket"},
+	{method: http.MethodPut, subresource: "acl", operation: "PutBucketAcl"},
+	{method: http.MethodPut, subresource: "tagging", operation: "PutBucketTagging"},
+	{method: http.MethodPut, subresource: "policy", operation: "PutBucketPolicy"},
+	{method: http.MethodPut, subresource: "versioning", operation: "PutBucketVersioning"},
+	{method: http.MethodPut, subresource: "cors", operation: "PutBucketCors"},
+	{method: http.MethodPut, subresource: "lifecycle", operation: "PutBucketLifecycleConfiguration"},
+	{method: http.MethodPut, subresource: "encryption", operation: "PutBucketEncryption"},
+	{method: http.MethodPut, subresource: "website", operation: "PutBucketWebsite"},
+	{method: http.MethodPut, subresource: "logging", operation: "PutBucketLogging"},
+	{method: http.MethodPut, subresource: "notification", operation: "PutBucketNotificationConfiguration"},
+	{method: http.MethodPut, subresource: "replication", operation: "PutBucketReplication"},
+	{method: http.MethodPut, subresource: "requestPayment", operation: "PutBucketRequestPayment"},
+	{method: http.MethodPut, subresource: "accelerate", operation: "PutBucketAccelerateConfiguration"},
+	{method: http.MethodPut, subresource: "ownershipControls", operation: "PutBucketOwnershipControls"},
+	{method: http.MethodPut, subresource: "publicAccessBlock", operation: "PutPublicAccessBlock"},
+	{method: http.MethodPut, subresource: "object-lock", operation: "PutObjectLockConfiguration"},
+	{method: http.MethodPut, operation: "CreateBucket"},
+	{method: http.MethodDelete, subresource: "tagging", operation: "DeleteBucketTagging"},
+	{method: http.MethodDelete, subresource: "policy", operation: "DeleteBucketPolicy"},
+	{method: http.MethodDelete, subresource: "cors", operation: "DeleteBucketCors"},
+	{method: http.MethodDelete, subresource: "lifecycle", operation: "DeleteBucketLifecycle"},
+	{method: http.MethodDelete, subresource: "encryption", operation: "DeleteBucketEncryption"},
+	{method: http.MethodDelete, subresource: "website", operation: "DeleteBucketWebsite"},
+	{method: http.MethodDelete, subresource: "replication", operation: "DeleteBucketReplication"},
+	{method: http.MethodDelete, subresource: "ownershipControls", operation: "DeleteBucketOwnershipControls"},
+	{method: http.MethodDelete, subresource: "publicAccessBlock", operation: "DeletePublicAccessBlock"},
+	{method: http.MethodDelete, operation: "DeleteBucket"},
+	{method: http.MethodPost, subresource: "delete", operation: "DeleteObjects"},
+	{method: http.MethodPost, operation: "PostObject"},
+
+	{method: http.MethodGet, object: true, subresource: "acl", operation: "GetObjectAcl"},
+	{method: http.MethodGet, object: true, subresource: "tagging", operation: "GetObjectTagging"},
+	{method: http.MethodGet, object: true, subresource: "attributes", operation: "GetObjectAttributes"},
+	{method: http.MethodGet, object: true, subresource: "uploadId", operation: "ListParts"},
+	{method: http.MethodGet, object: true, subresource: "retention", operation: "GetObjectRetention"},
+	{method: http.MethodGet, object: true, subresource: "legal-hold", operation: "GetObjectLegalHold"},
+	{method: http.MethodGet, object: true, subresource: "torrent", operation: "GetObjectTorrent"},
+	{method: http.MethodGet, object: true, operation: "GetObject"},
+	{method: http.MethodHead, object: true, operation: "HeadObject"},
+	{method: http.MethodPut, object: true, subresource: "acl", operation: "PutObjectAcl"},
+	{method: http.MethodPut, object: true, subresource: "tagging", operation: "PutObjectTagging"},
+	{method: http.MethodPut, object: true, subresource: "retention", operation: "PutObjectRetention"},
+	{method: http.MethodPut, object: true, subresource: "legal-hold", operation: "PutObjectLegalHold"},
+	{method: http.MethodPut, object: true, subresource: "uploadId", operation: "UploadPart"},
+	{method: http.MethodPut, object: true, operation: "PutObject"},
+	{method: http.MethodPost, object: true, subresource: "uploads", operation: "CreateMultipartUpload"},
+	{method: http.MethodPost, object: true, subresource: "uploadId", operation: "CompleteMultipartUpload"},
+	{method: http.MethodPost, object: true, subresource: "restore", operation: "RestoreObject"},
+	{method: http.MethodPost, object: true, subresource: "select", operation: "SelectObjectContent"},
+	{method: http.MethodDelete, object: true, subresource: "tagging", operation: "DeleteObjectTagging"},
+	{method: http.MethodDelete, object: true, subresource: "uploadId", operation: "AbortMultipartUpload"},
+	{method: http.MethodDelete, object: true, operation: "DeleteObject"},
+}
+
+// plainParameters are the query parameters of requests without subresource, e.g. GetObject or ListObjects.
+// Requests with other parameters are subresources missing from operations, their operation is unknown.
+var plainParameters = map[string]bool{
+	"versionId":                    true,
+	"partNumber":                   true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"prefix":                       true,
+	"delimiter":                    true,
+	"marker":                       true,
+	"max-keys":                     true,
+	"encoding-type":                true,
+	// x-id names the operation in aws sdk requests, the others authenticate presigned urls
+	"x-id":           true,
+	"AWSAccessKeyId": true,
+	"Signature":      true,
+	"Expires":        true,
+}
+
+// hasSubresource returns true if query has parameters other than plainParameters.
+func hasSubresource(query url.Values) bool {
+	for key := range query {
+		if !plainParameters[key] && !strings.HasPrefix(strings.ToLower(key), "x-amz-") {
+			return true
+		}
+	}
+	return false
+}
+
+// readOperations are the operations that do not modify buckets or objects.
+var readOperations = func() map[string]bool {
+	ret := map[string]bool{"SelectObjectContent": true}
+	for _, op := range operations {
+		if op.method == http.MethodGet || op.method == http.MethodHead {
+			ret[op.operation] = true
+		}
+	}
+	return ret
+}()
+
+// IsReadOperation returns true if operation does not modify buckets or objects. Unknown operations are not reads.
+func IsReadOperation(operation string) bool {
+	return readOperations[operation]
+}
+
+// UnknownOperation is the operation of requests that match no S3 api operation.
+const UnknownOperation = "Unknown"
+
+// Operation returns the S3 api operation of req, e.g. "GetObject" or "ListObjectsV2".
+func Operation(req *http.Request, sourceBucket SourceBucket) string {
+	query := req.URL.Query()
+	object := sourceBucket.Key != ""
+	for _, op := range operations {
+		if op.method != req.Method || op.object != object {
+			continue
+		}
+		if op.subresource != "" && !query.Has(op.subresource) {
+			continue
+		}
+		if op.subresource == "" && hasSubresource(query) {
+			return UnknownOperation
+		}
+		// copies are puts naming their source in a header
+		if req.Header.Get(CopySourceHeader) != "" {
+			switch op.operation {
+			case "PutObject":
+				return "CopyObject"
+			case "UploadPart":
+				return "UploadPartCopy"
+			}
+		}
+		return op.operation
+	}
+	return UnknownOperation
+}
@@ -0,0 +1,68 @@
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAws_Operation(t *testing.T) {
+	for _, tc := range []struct {
+		method     string
+		target     string
+		copySource string
+		expected   string
+	}{
+		{method: http.MethodGet, target: "/bucket/key", expected: "GetObject"},
+		{method: http.MethodHead, target: "/bucket/dir/key", expected: "HeadObject"},
+		{method: http.MethodGet, target: "/bucket?list-type=2&prefix=dir/", expected: "ListObjectsV2"},
+		{method: http.MethodGet, target: "/bucket/", expected: "ListObjects"},
+		{method: http.MethodGet, target: "/bucket/key?tagging", expected: "GetObjectTagging"},
+		{method: http.MethodPut, target: "/bucket/key", expected: "PutObject"},
+		{method: http.MethodPut, target: "/bucket/key", copySource: "/other/key", expected: "CopyObject"},
+		{method: http.MethodPut, target: "/bucket/key?partNumber=1&uploadId=id", expected: "UploadPart"},
+		{method: http.MethodPut, target: "/bucket/key?partNumber=1&uploadId=id", copySource: "/other/key", expected: "UploadPartCopy"},
+		{method: http.MethodPut, target: "/bucket/key?acl", expected: "PutObjectAcl"},
+		{method: http.MethodPost, target: "/bucket/key?uploads", expected: "CreateMultipartUpload"},
+		{method: http.MethodPost, target: "/bucket/key?uploadId=id", expected: "CompleteMultipartUpload"},
+		{method: http.MethodPost, target: "/bucket?delete", expected: "DeleteObjects"},
+		{method: http.MethodDelete, target: "/bucket/key", expected: "DeleteObject"},
+		{method: http.MethodDelete, target: "/bucket/key?uploadId=id", expected: "AbortMultipartUpload"},
+		{method: http.MethodPut, target: "/bucket?tagging", expected: "PutBucketTagging"},
+		{method: http.MethodGet, target: "/bucket/key?versionId=1&partNumber=2&response-content-type=text/plain&x-id=GetObject", expected: "GetObject"},
+		{method: http.MethodGet, target: "/bucket/key?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Signature=sig", expected: "GetObject"},
+		{method: http.MethodGet, target: "/bucket/key?retention", expected: "GetObjectRetention"},
+		{method: http.MethodGet, target: "/bucket/key?legal-hold", expected: "GetObjectLegalHold"},
+		{method: http.MethodGet, target: "/bucket/key?torrent", expected: "GetObjectTorrent"},
+		{method: http.MethodGet, target: "/bucket?encryption", expected: "GetBucketEncryption"},
+		{method: http.MethodGet, target: "/bucket?policy", expected: "GetBucketPolicy"},
+		{method: http.MethodGet, target: "/bucket?prefix=dir/&delimiter=/&max-keys=10", expected: "ListObjects"},
+		{method: http.MethodGet, target: "/bucket?intelligent-tiering", expected: UnknownOperation},
+		{method: http.MethodGet, target: "/bucket/key?unknown", expected: UnknownOperation},
+		{method: http.MethodPut, target: "/bucket?encryption", expected: "PutBucketEncryption"},
+		{method: http.MethodPatch, target: "/bucket/key", expected: UnknownOperation},
+	} {
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://localhost:7075"+tc.target, nil)
+			req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA/20230511/us-east-1/s3/aws4_request")
+			if tc.copySource != "" {
+				req.Header.Set(CopySourceHeader, tc.copySource)
+			}
+			sourceBucket, err := ExtractSourceBucket(req)
+			assert.NoError(t, err)
+			assert.Equal(t, "bucket", sourceBucket.Bucket)
+			assert.Equal(t, tc.expected, Operation(req, sourceBucket))
+		})
+	}
+}
+
+func TestAws_IsReadOperation(t *testing.T) {
+	for _, operation := range []string{"GetObject", "HeadObject", "ListObjectsV2", "GetObjectTagging", "ListParts", "SelectObjectContent", "GetObjectRetention", "GetBucketEncryption"} {
+		assert.True(t, IsReadOperation(operation), operation)
+	}
+	for _, operation := range []string{"PutObject", "CopyObject", "DeleteObject", "DeleteObjects", "CreateMultipartUpload", "UploadPart", "PutObjectAcl", "PutObjectTagging", "PostObject", UnknownOperation} {
+		assert.False(t, IsReadOperation(operation), operation)
+	}
+}
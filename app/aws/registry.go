@@ -0,0 +1,294 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.uber.org/zap"
+)
+
+// Registry holds the aws sdk configs, credentials and s3 clients used to reach upstream.
+// Credentials are built once per CredentialsSource, sdk configs and s3 clients once per source and region.
+type Registry struct {
+	options RegistryOptions
+
+	lock        sync.Mutex
+	credentials map[string]*credentialsEntry
+	clients     map[clientKey]*clientEntry
+	regions     map[string]bool
+
+	credentialsHits   atomic.Int64
+	credentialsBuilds atomic.Int64
+	clientHits        atomic.Int64
+	clientBuilds      atomic.Int64
+	refreshes         atomic.Int64
+	refreshErrors     atomic.Int64
+}
+
+type credentialsEntry struct {
+	lock   sync.Mutex
+	source CredentialsSource
+	cache  *aws.CredentialsCache
+	// built is set once cache is, it is read without lock by Stats
+	built atomic.Bool
+}
+
+type clientKey struct {
+	source string
+	region string
+}
+
+type clientEntry struct {
+	lock      sync.Mutex
+	awsConfig aws.Config
+	client    *s3.Client
+	// built is set once client is, it is read without lock by Stats
+	built atomic.Bool
+}
+
+// RegistryStats counts the entries of a Registry and how often they were built or reused.
+type RegistryStats struct {
+	Credentials       int
+	Clients           int
+	Regions           []string
+	CredentialsHits   int64
+	CredentialsBuilds int64
+	ClientHits        int64
+	ClientBuilds      int64
+	Refreshes         int64
+	RefreshErrors     int64
+}
+
+type RegistryOptions struct {
+	defaultCredentials aws.CredentialsProvider
+	httpClient         *http.Client
+}
+
+// WithDefaultCredentials replaces the default credential chain with provider.
+func WithDefaultCredentials(provider aws.CredentialsProvider) func(*RegistryOptions) {
+	return func(o *RegistryOptions) {
+		o.defaultCredentials = provider
+	}
+}
+
+// WithHTTPClient sets the http client of the s3 clients of the registry.
+func WithHTTPClient(client *http.Client) func(*RegistryOptions) {
+	return func(o *RegistryOptions) {
+		o.httpClient = client
+	}
+}
+
+func NewRegistry(opts ...func(*RegistryOptions)) *Registry {
+	options := RegistryOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Registry{
+		options:     options,
+		credentials: map[string]*credentialsEntry{},
+		clients:     map[clientKey]*clientEntry{},
+		regions:     map[string]bool{},
+	}
+}
+
+// CredentialsProvider returns the credentials cache of source, creating it on first use.
+// The cache refreshes credentials ahead of their expiry, concurrent refreshes are deduplicated.
+// region is the region of the sts endpoint used to assume roles.
+func (r *Registry) CredentialsProvider(ctx context.Context, source CredentialsSource, region string) (*aws.CredentialsCache, error) {
+	r.lock.Lock()
+	entry, ok := r.credentials[source.Key()]
+	if !ok {
+		entry = &credentialsEntry{source: source}
+		r.credentials[source.Key()] = entry
+	}
+	r.lock.Unlock()
+
+	// entries are built outside of the registry lock, so that a slow source does not block the others
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.cache != nil {
+		r.credentialsHits.Add(1)
+		return entry.cache, nil
+	}
+
+	provider := r.options.defaultCredentials
+	if source != DefaultCredentialsSource || provider == nil {
+		awsConfig, err := source.loadConfig(ctx, region)
+		if err != nil {
+			return nil, err
+		}
+		provider = awsConfig.Credentials
+	}
+	cache, ok := provider.(*aws.CredentialsCache)
+	if !ok {
+		cache = aws.NewCredentialsCache(provider, credentialsCacheOptions)
+	}
+	entry.cache = cache
+	entry.built.Store(true)
+	r.credentialsBuilds.Add(1)
+	return cache, nil
+}
+
+// Credentials returns the aws credentials of source, to sign requests to region.
+func (r *Registry) Credentials(ctx context.Context, source CredentialsSource, region string) (aws.Credentials, error) {
+	r.lock.Lock()
+	r.regions[region] = true
+	r.lock.Unlock()
+
+	cache, err := r.CredentialsProvider(ctx, source, region)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	cred, err := cache.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("could not retrieve aws credentials from %s: %w", source, err)
+	}
+	return cred, nil
+}
+
+// Invalidate forces the credentials of source to be refreshed on their next use.
+func (r *Registry) Invalidate(source CredentialsSource) {
+	r.lock.Lock()
+	entry, ok := r.credentials[source.Key()]
+	r.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.cache != nil {
+		entry.cache.Invalidate()
+	}
+}
+
+// S3Client returns an s3 client for region, signing with the credentials of source.
+func (r *Registry) S3Client(ctx context.Context, source CredentialsSource, region string) (*s3.Client, error) {
+	key := clientKey{source: source.Key(), region: region}
+	r.lock.Lock()
+	entry, ok := r.clients[key]
+	if !ok {
+		entry = &clientEntry{}
+		r.clients[key] = entry
+	}
+	r.lock.Unlock()
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.client != nil {
+		r.clientHits.Add(1)
+		return entry.client, nil
+	}
+
+	credentials, err := r.CredentialsProvider(ctx, source, region)
+	if err != nil {
+		return nil, err
+	}
+	awsConfig, err := source.loadConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	awsConfig.Credentials = credentials
+	if r.options.httpClient != nil {
+		awsConfig.HTTPClient = r.options.httpClient
+	}
+	entry.awsConfig = awsConfig
+	entry.client = s3.NewFromConfig(awsConfig)
+	entry.built.Store(true)
+	r.clientBuilds.Add(1)
+	return entry.client, nil
+}
+
+// Regions returns the regions credentials have been requested for.
+func (r *Registry) Regions() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ret := make([]string, 0, len(r.regions))
+	for region := range r.regions {
+		ret = append(ret, region)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Stats returns the current statistics of the registry.
+func (r *Registry) Stats() RegistryStats {
+	r.lock.Lock()
+	credentials := 0
+	for _, entry := range r.credentials {
+		if entry.built.Load() {
+			credentials++
+		}
+	}
+	clients := 0
+	for _, entry := range r.clients {
+		if entry.built.Load() {
+			clients++
+		}
+	}
+	r.lock.Unlock()
+
+	return RegistryStats{
+		Credentials:       credentials,
+		Clients:           clients,
+		Regions:           r.Regions(),
+		CredentialsHits:   r.credentialsHits.Load(),
+		CredentialsBuilds: r.credentialsBuilds.Load(),
+		ClientHits:        r.clientHits.Load(),
+		ClientBuilds:      r.clientBuilds.Load(),
+		Refreshes:         r.refreshes.Load(),
+		RefreshErrors:     r.refreshErrors.Load(),
+	}
+}
+
+// Refresh retrieves the credentials of every source. Credentials within their expiry window are refreshed,
+// others are returned from the cache.
+func (r *Registry) Refresh(ctx context.Context, logger *zap.Logger) {
+	r.lock.Lock()
+	entries := make([]*credentialsEntry, 0, len(r.credentials))
+	for _, entry := range r.credentials {
+		entries = append(entries, entry)
+	}
+	r.lock.Unlock()
+
+	for _, entry := range entries {
+		entry.lock.Lock()
+		cache := entry.cache
+		entry.lock.Unlock()
+		if cache == nil {
+			continue
+		}
+
+		r.refreshes.Add(1)
+		if _, err := cache.Retrieve(ctx); err != nil {
+			r.refreshErrors.Add(1)
+			logger.Error(fmt.Sprintf("aws credential refresh failed for %s", entry.source), zap.Error(err))
+		}
+	}
+}
+
+// RefreshPeriodically refreshes credentials in the background, until ctx is done.
+func (r *Registry) RefreshPeriodically(ctx context.Context, logger *zap.Logger) {
+	r.Refresh(ctx, logger)
+	ticker := time.NewTicker(credentialsRefreshInterval)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				r.Refresh(ctx, logger)
+			}
+		}
+	}()
+}
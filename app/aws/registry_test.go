@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAws_Registry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	static := CredentialsSource{AccessKeyId: "static_key", SecretAccessKey: "static_secret"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := registry.S3Client(ctx, DefaultCredentialsSource, "us-east-1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	east, err := registry.S3Client(ctx, DefaultCredentialsSource, "us-east-1")
+	require.NoError(t, err)
+	west, err := registry.S3Client(ctx, DefaultCredentialsSource, "us-west-2")
+	require.NoError(t, err)
+	assert.NotSame(t, east, west)
+	staticEast, err := registry.S3Client(ctx, static, "us-east-1")
+	require.NoError(t, err)
+	assert.NotSame(t, east, staticEast)
+
+	_, err = registry.Credentials(ctx, static, "eu-west-1")
+	require.NoError(t, err)
+
+	stats := registry.Stats()
+	assert.Equal(t, 2, stats.Credentials)
+	assert.Equal(t, 3, stats.Clients)
+	assert.Equal(t, int64(3), stats.ClientBuilds)
+	assert.Equal(t, int64(10), stats.ClientHits)
+	assert.Equal(t, int64(2), stats.CredentialsBuilds)
+	assert.Equal(t, []string{"eu-west-1"}, stats.Regions)
+
+	// two registries do not share anything
+	assert.Equal(t, 0, NewRegistry().Stats().Clients)
+
+	registry.Refresh(ctx, nil)
+	assert.Equal(t, int64(2), registry.Stats().Refreshes)
+	assert.Equal(t, int64(0), registry.Stats().RefreshErrors)
+}
+
+func TestAws_RegistryConcurrentStats(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		source := CredentialsSource{AccessKeyId: fmt.Sprintf("key_%d", i), SecretAccessKey: "secret"}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := registry.Credentials(ctx, source, "us-east-1")
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := registry.S3Client(ctx, source, "us-west-2")
+			assert.NoError(t, err)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		stats := registry.Stats()
+		select {
+		case <-done:
+			stats = registry.Stats()
+			assert.Equal(t, 10, stats.Credentials)
+			assert.Equal(t, 10, stats.Clients)
+			return
+		default:
+			assert.LessOrEqual(t, stats.Clients, 10)
+		}
+	}
+}
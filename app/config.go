@@ -1,6 +1,12 @@
 package app
 
-import "fmt"
+import (
+	"fmt"
+	"path"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/project-n-oss/sidekick/pkg/config"
+)
 
 type CloudPlatformType string
 
@@ -14,14 +20,162 @@ func (c CloudPlatformType) String() string {
 }
 
 type Config struct {
-	CloudPlatform string `yaml:"CloudPlatform"`
+	CloudPlatform string `yaml:"CloudPlatform" reload:"restart"`
 	NoCrunchErr   bool   `yaml:"NoCrunchErr"`
+	// Regions are the aws regions checked for readiness.
+	// If empty, the regions requests have been made to so far are checked.
+	Regions []string `yaml:"Regions"`
+	// Credentials map buckets to the aws credentials they are accessed with. The first matching entry is used,
+	// buckets that match no entry use the default credential chain.
+	Credentials []CredentialsConfig `yaml:"Credentials"`
+	// SkipDefaultCredentialsCheck does not check the default credential chain for readiness, for deployments
+	// where it is not available and every bucket accessed has Credentials.
+	SkipDefaultCredentialsCheck bool `yaml:"SkipDefaultCredentialsCheck"`
+	// ReadOnly rejects every request that modifies buckets or objects.
+	ReadOnly bool `yaml:"ReadOnly"`
+	// ReadOnlyBuckets are bucket names or globs that are read-only, e.g. "analytics-*".
+	ReadOnlyBuckets []string `yaml:"ReadOnlyBuckets"`
+	// Routes map logical buckets and key prefixes to the physical buckets, prefixes and regions they are stored in.
+	Routes []RouteConfig `yaml:"Routes"`
+	// Failover lists the replicas reads fail over to, per bucket.
+	Failover []FailoverConfig `yaml:"Failover"`
+	// Policy restricts the buckets, keys and operations clients can access.
+	Policy PolicyConfig `yaml:"Policy"`
+}
+
+// CredentialsConfig is a source of aws credentials for some buckets.
+type CredentialsConfig struct {
+	// Buckets are bucket names or globs, e.g. "logs-*".
+	Buckets []string `yaml:"Buckets"`
+	// Profile is a named profile of the shared aws config and credentials files.
+	Profile string `yaml:"Profile"`
+	// AccessKeyId, SecretAccessKey and SessionToken are static credentials.
+	AccessKeyId     config.SecretValue `yaml:"AccessKeyId"`
+	SecretAccessKey config.SecretValue `yaml:"SecretAccessKey"`
+	SessionToken    config.SecretValue `yaml:"SessionToken"`
+	// CredentialProcess is a command printing credentials as json on stdout, in the aws credential_process
+	// format or as {"access_key_id", "secret_access_key", "session_token", "expiration"}.
+	CredentialProcess string `yaml:"CredentialProcess"`
+	// RoleArn is assumed with sts, using the credentials above or WebIdentityTokenFile.
+	RoleArn         string `yaml:"RoleArn"`
+	ExternalId      string `yaml:"ExternalId"`
+	RoleSessionName string `yaml:"RoleSessionName"`
+	// WebIdentityTokenFile is an OIDC token file exchanged for RoleArn credentials.
+	WebIdentityTokenFile string `yaml:"WebIdentityTokenFile"`
+}
+
+func (c CredentialsConfig) Validate() error {
+	if len(c.Buckets) == 0 {
+		return fmt.Errorf("Buckets must not be empty")
+	}
+	for _, bucket := range c.Buckets {
+		if _, err := path.Match(bucket, ""); err != nil {
+			return fmt.Errorf("invalid bucket pattern %q: %w", bucket, err)
+		}
+	}
+	if c.AccessKeyId.IsZero() != c.SecretAccessKey.IsZero() {
+		return fmt.Errorf("AccessKeyId and SecretAccessKey must be set together")
+	}
+	sources := 0
+	for _, set := range []bool{c.Profile != "", !c.AccessKeyId.IsZero(), c.CredentialProcess != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of Profile, AccessKeyId and CredentialProcess can be set")
+	}
+	if c.RoleArn == "" && (c.WebIdentityTokenFile != "" || c.ExternalId != "" || c.RoleSessionName != "") {
+		return fmt.Errorf("WebIdentityTokenFile, ExternalId and RoleSessionName require a RoleArn")
+	}
+	return nil
+}
+
+// source returns the credentials source described by c.
+func (c CredentialsConfig) source() sidekickAws.CredentialsSource {
+	return sidekickAws.CredentialsSource{
+		Profile:              c.Profile,
+		AccessKeyId:          c.AccessKeyId.Value(),
+		SecretAccessKey:      c.SecretAccessKey.Value(),
+		SessionToken:         c.SessionToken.Value(),
+		CredentialProcess:    c.CredentialProcess,
+		RoleArn:              c.RoleArn,
+		ExternalId:           c.ExternalId,
+		RoleSessionName:      c.RoleSessionName,
+		WebIdentityTokenFile: c.WebIdentityTokenFile,
+	}
+}
+
+// CredentialsSource returns the source of the credentials bucket is accessed with.
+func (c Config) CredentialsSource(bucket string) sidekickAws.CredentialsSource {
+	for _, credentials := range c.Credentials {
+		for _, pattern := range credentials.Buckets {
+			if ok, _ := path.Match(pattern, bucket); ok {
+				return credentials.source()
+			}
+		}
+	}
+	return sidekickAws.DefaultCredentialsSource
+}
+
+// usesDefaultCredentials returns true if some buckets can fall back to the default credential chain,
+// i.e. no Credentials entry matches every bucket and the default chain check is not skipped.
+func (c Config) usesDefaultCredentials() bool {
+	if c.SkipDefaultCredentialsCheck {
+		return false
+	}
+	for _, credentials := range c.Credentials {
+		for _, pattern := range credentials.Buckets {
+			if pattern == "*" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsReadOnly returns true if bucket cannot be modified through sidekick.
+func (c Config) IsReadOnly(bucket string) bool {
+	if c.ReadOnly {
+		return true
+	}
+	for _, pattern := range c.ReadOnlyBuckets {
+		if ok, _ := path.Match(pattern, bucket); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (c Config) Validate() error {
 	if c.CloudPlatform != AwsCloudPlatform.String() && c.CloudPlatform != GcpCloudPlatform.String() {
 		return fmt.Errorf("CloudPlatform must be one of: %s, %s", AwsCloudPlatform, GcpCloudPlatform)
 	}
+	for i, credentials := range c.Credentials {
+		if err := credentials.Validate(); err != nil {
+			return fmt.Errorf("Credentials.%d: %w", i, err)
+		}
+	}
+	for _, bucket := range c.ReadOnlyBuckets {
+		if _, err := path.Match(bucket, ""); err != nil {
+			return fmt.Errorf("ReadOnlyBuckets: invalid bucket pattern %q: %w", bucket, err)
+		}
+	}
+	for i, route := range c.Routes {
+		if err := route.Validate(); err != nil {
+			return fmt.Errorf("Routes.%d: %w", i, err)
+		}
+	}
+	for i, failover := range c.Failover {
+		if err := failover.Validate(); err != nil {
+			return fmt.Errorf("Failover.%d: %w", i, err)
+		}
+	}
+	for i, rule := range c.Policy.Rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("Policy.Rules.%d: %w", i, err)
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"testing"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/project-n-oss/sidekick/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApp_CredentialsSource(t *testing.T) {
+	cfg := Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		Credentials: []CredentialsConfig{
+			{Buckets: []string{"logs-*"}, RoleArn: "arn:aws:iam::123456789012:role/logs", ExternalId: "external"},
+			{Buckets: []string{"logs-static", "archive"}, AccessKeyId: config.NewSecretValue("key"), SecretAccessKey: config.NewSecretValue("secret")},
+			{Buckets: []string{"*-dev"}, Profile: "dev"},
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	assert.Equal(t, "arn:aws:iam::123456789012:role/logs", cfg.CredentialsSource("logs-static").RoleArn)
+	assert.Equal(t, "external", cfg.CredentialsSource("logs-2023").ExternalId)
+	assert.Equal(t, "key", cfg.CredentialsSource("archive").AccessKeyId)
+	assert.Equal(t, "dev", cfg.CredentialsSource("data-dev").Profile)
+	assert.Equal(t, sidekickAws.DefaultCredentialsSource, cfg.CredentialsSource("data"))
+
+	for name, credentials := range map[string]CredentialsConfig{
+		"NoBuckets":        {Profile: "dev"},
+		"BadPattern":       {Buckets: []string{"logs-["}},
+		"MissingSecret":    {Buckets: []string{"logs"}, AccessKeyId: config.NewSecretValue("key")},
+		"ProfileAndKeys":   {Buckets: []string{"logs"}, Profile: "dev", AccessKeyId: config.NewSecretValue("key"), SecretAccessKey: config.NewSecretValue("secret")},
+		"ExternalIdNoRole": {Buckets: []string{"logs"}, ExternalId: "external"},
+	} {
+		cfg := Config{CloudPlatform: AwsCloudPlatform.String(), Credentials: []CredentialsConfig{credentials}}
+		assert.Error(t, cfg.Validate(), name)
+	}
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+)
+
+// Errors returned by Session.DoRequest, the api layer matches them with errors.Is to build error responses.
+var (
+	ErrUnsupportedCloudPlatform = errors.New("cloud platform not supported")
+	ErrSourceBucket             = errors.New("failed to extract source bucket from request")
+	ErrSignRequest              = errors.New("failed to make upstream request")
+	ErrUpstreamTransport        = errors.New("failed to do upstream request")
+	ErrUpstreamTimeout          = errors.New("upstream request timed out")
+	ErrCrunchLocked             = errors.New("src file not found, but crunched file also found")
+	ErrAccessDenied             = errors.New("access denied")
+	ErrReadOnly                 = errors.New("bucket is read-only")
+)
+
+// ErrorKind classifies errors, so that they can be reported with the right status code.
+type ErrorKind int
+
+const (
+	// InternalErrorKind is a bug or a misconfiguration of sidekick.
+	InternalErrorKind ErrorKind = iota
+	// ClientErrorKind is a malformed client request, retrying it will not help.
+	ClientErrorKind
+	// AuthErrorKind is a client request that cannot be authenticated.
+	AuthErrorKind
+	// UpstreamUnavailableErrorKind is an upstream that could not be reached.
+	UpstreamUnavailableErrorKind
+	// UnavailableErrorKind is sidekick not being able to serve the request for now, e.g. credentials
+	// could not be loaded. The request can be retried.
+	UnavailableErrorKind
+	// TimeoutErrorKind is an upstream that did not respond in time.
+	TimeoutErrorKind
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ClientErrorKind:
+		return "client"
+	case AuthErrorKind:
+		return "auth"
+	case UpstreamUnavailableErrorKind:
+		return "upstream_unavailable"
+	case UnavailableErrorKind:
+		return "unavailable"
+	case TimeoutErrorKind:
+		return "timeout"
+	default:
+		return "internal"
+	}
+}
+
+// Error is an error with a kind.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, err error) error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of err. Errors without a kind are internal errors.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return InternalErrorKind
+}
+
+// sourceBucketError wraps an error returned while extracting the source bucket of a request.
+func sourceBucketError(err error) error {
+	err = fmt.Errorf("%w: %w", ErrSourceBucket, err)
+	if errors.Is(err, sidekickAws.ErrMissingAuthHeader) || errors.Is(err, sidekickAws.ErrInvalidAuthHeader) {
+		return newError(AuthErrorKind, err)
+	}
+	return newError(ClientErrorKind, err)
+}
+
+// signRequestError wraps an error returned while creating the upstream request.
+func signRequestError(err error) error {
+	err = fmt.Errorf("%w: %w", ErrSignRequest, err)
+	if errors.Is(err, sidekickAws.ErrCredentials) {
+		return newError(UnavailableErrorKind, err)
+	}
+	return newError(InternalErrorKind, err)
+}
+
+// upstreamError wraps an error returned by the upstream http client, distinguishing timeouts
+// and requests cancelled by the client.
+func upstreamError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return newError(ClientErrorKind, fmt.Errorf("%w: %w", ErrUpstreamTransport, err))
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return newError(TimeoutErrorKind, fmt.Errorf("%w: %w", ErrUpstreamTimeout, err))
+	}
+	return newError(UpstreamUnavailableErrorKind, fmt.Errorf("%w: %w", ErrUpstreamTransport, err))
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApp_ErrorKind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+		is       error
+	}{
+		{name: "missing auth header", err: sourceBucketError(sidekickAws.ErrMissingAuthHeader), expected: AuthErrorKind, is: ErrSourceBucket},
+		{name: "no bucket", err: sourceBucketError(errors.New("could not extract bucket from request")), expected: ClientErrorKind, is: ErrSourceBucket},
+		{name: "credentials", err: signRequestError(fmt.Errorf("%w: imds unreachable", sidekickAws.ErrCredentials)), expected: UnavailableErrorKind, is: ErrSignRequest},
+		{name: "signing", err: signRequestError(errors.New("signing failed")), expected: InternalErrorKind, is: ErrSignRequest},
+		{name: "timeout", err: upstreamError(fmt.Errorf("get: %w", context.DeadlineExceeded)), expected: TimeoutErrorKind, is: ErrUpstreamTimeout},
+		{name: "cancelled", err: upstreamError(fmt.Errorf("get: %w", context.Canceled)), expected: ClientErrorKind, is: ErrUpstreamTransport},
+		{name: "transport", err: upstreamError(http.ErrHandlerTimeout), expected: UpstreamUnavailableErrorKind, is: ErrUpstreamTransport},
+		{name: "no kind", err: errors.New("unknown"), expected: InternalErrorKind},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, KindOf(tc.err))
+			if tc.is != nil {
+				assert.ErrorIs(t, tc.err, tc.is)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"go.uber.org/zap"
+)
+
+// ReplicaHeader is the response header naming the replica bucket that served a read, when the primary bucket failed.
+const ReplicaHeader = "X-Sidekick-Replica"
+
+// FailoverConfig lists the replicas reads of a bucket fail over to.
+type FailoverConfig struct {
+	// Bucket is the primary bucket, after routing.
+	Bucket string `yaml:"Bucket"`
+	// Replicas are tried in order when a GET or HEAD request to the previous bucket fails with a 5xx status code,
+	// cannot reach it or times out.
+	Replicas []ReplicaConfig `yaml:"Replicas"`
+	// TimeoutMilliseconds bounds the time to the response headers of each bucket but the last one.
+	// 0 disables it, only errors then fail over.
+	TimeoutMilliseconds int `yaml:"TimeoutMilliseconds"`
+}
+
+// ReplicaConfig is a replica of a bucket, e.g. the destination of a replication rule.
+type ReplicaConfig struct {
+	Bucket string `yaml:"Bucket"`
+	// Region is the region of Bucket, requests are signed for it. Defaults to the region of the primary bucket.
+	Region string `yaml:"Region"`
+}
+
+func (c FailoverConfig) Validate() error {
+	if c.Bucket == "" {
+		return fmt.Errorf("Bucket must be set")
+	}
+	if len(c.Replicas) == 0 {
+		return fmt.Errorf("Replicas must not be empty")
+	}
+	for i, replica := range c.Replicas {
+		if replica.Bucket == "" {
+			return fmt.Errorf("Replicas.%d: Bucket must be set", i)
+		}
+	}
+	if c.TimeoutMilliseconds < 0 {
+		return fmt.Errorf("TimeoutMilliseconds must not be negative")
+	}
+	return nil
+}
+
+// BucketFailover returns the failover config of bucket.
+func (c Config) BucketFailover(bucket string) (FailoverConfig, bool) {
+	for _, failover := range c.Failover {
+		if failover.Bucket == bucket {
+			return failover, true
+		}
+	}
+	return FailoverConfig{}, false
+}
+
+// upstreamResponse is the response of the bucket that served a request.
+type upstreamResponse struct {
+	cloudRequest      *http.Request
+	resp              *http.Response
+	sourceBucket      sidekickAws.SourceBucket
+	credentialsSource sidekickAws.CredentialsSource
+	// replica is the replica that served the request, nil for the primary bucket.
+	replica *ReplicaConfig
+}
+
+// doAwsRequestWithFailover sends req upstream. Reads failing on buckets with replicas are retried on the replicas.
+func (sess *Session) doAwsRequestWithFailover(req *http.Request, sourceBucket sidekickAws.SourceBucket, operation string) (upstreamResponse, error) {
+	failover, ok := sess.cfg.BucketFailover(sourceBucket.Bucket)
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return sess.doAwsRequest(sess.Context(), req, sourceBucket)
+	}
+
+	primaryBucket := sourceBucket.Bucket
+	for i := 0; ; i++ {
+		var replica *ReplicaConfig
+		targetReq, targetBucket := req, sourceBucket
+		if i > 0 {
+			replica = &failover.Replicas[i-1]
+			route := RouteConfig{Bucket: primaryBucket, TargetBucket: replica.Bucket, TargetRegion: replica.Region}
+			targetReq, targetBucket = route.apply(req, sourceBucket, operation)
+		}
+		last := i == len(failover.Replicas)
+
+		ctx, cancel := context.WithCancel(sess.Context())
+		var timer *time.Timer
+		if failover.TimeoutMilliseconds > 0 && !last {
+			timer = time.AfterFunc(time.Duration(failover.TimeoutMilliseconds)*time.Millisecond, cancel)
+		}
+		upstream, err := sess.doAwsRequest(ctx, targetReq, targetBucket)
+		// the timer can fire right after the response headers, the body could not be read anymore
+		if timer != nil && !timer.Stop() {
+			if err == nil {
+				upstream.resp.Body.Close()
+			}
+			err = newError(TimeoutErrorKind, fmt.Errorf("%w: no response from %s within %dms", ErrUpstreamTimeout, targetBucket.Bucket, failover.TimeoutMilliseconds))
+		}
+
+		failed := err != nil && (KindOf(err) == UpstreamUnavailableErrorKind || KindOf(err) == TimeoutErrorKind)
+		if err == nil && upstream.resp.StatusCode >= http.StatusInternalServerError {
+			failed = true
+		}
+		if last || !failed || sess.Context().Err() != nil {
+			if err != nil {
+				cancel()
+				return upstreamResponse{}, err
+			}
+			// the context must outlive the request, until the body is read
+			upstream.resp.Body = &cancelOnClose{ReadCloser: upstream.resp.Body, cancel: cancel}
+			upstream.replica = replica
+			if replica != nil {
+				sess.WithLogger(sess.Logger().With(zap.String("replicaBucket", targetBucket.Bucket), zap.String("replicaRegion", targetBucket.Region)))
+				upstream.resp.Header.Set(ReplicaHeader, targetBucket.Bucket)
+			}
+			return upstream, nil
+		}
+
+		reason := zap.Error(err)
+		if err == nil {
+			reason = zap.Int("statusCode", upstream.resp.StatusCode)
+			upstream.resp.Body.Close()
+		}
+		cancel()
+		sess.Logger().Warn("read failed, failing over to the next replica", zap.String("bucket", targetBucket.Bucket), zap.String("region", targetBucket.Region), reason)
+	}
+}
+
+// doAwsRequest signs req for sourceBucket and sends it upstream with ctx.
+func (sess *Session) doAwsRequest(ctx context.Context, req *http.Request, sourceBucket sidekickAws.SourceBucket) (upstreamResponse, error) {
+	credentialsSource := sess.cfg.CredentialsSource(sourceBucket.Bucket)
+	cloudRequest, resp, err := sess.sendAwsRequest(ctx, req, sourceBucket, credentialsSource)
+	if err != nil {
+		return upstreamResponse{}, err
+	}
+	// credentials can be revoked or expire earlier than announced, refresh them and retry once
+	if sidekickAws.IsExpiredToken(resp) {
+		resp.Body.Close()
+		sess.Logger().Warn("upstream rejected expired aws credentials, refreshing them", zap.Stringer("credentials", credentialsSource))
+		sess.app.awsRegistry.Invalidate(credentialsSource)
+		cloudRequest, resp, err = sess.sendAwsRequest(ctx, req, sourceBucket, credentialsSource)
+		if err != nil {
+			return upstreamResponse{}, err
+		}
+	}
+	return upstreamResponse{cloudRequest: cloudRequest, resp: resp, sourceBucket: sourceBucket, credentialsSource: credentialsSource}, nil
+}
+
+// cancelOnClose cancels the context of a response when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
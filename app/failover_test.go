@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestApp_Failover(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "foobar_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "foobar_secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	var upstreamRequests []*http.Request
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		lock.Lock()
+		upstreamRequests = append(upstreamRequests, req)
+		lock.Unlock()
+		switch {
+		case strings.HasPrefix(req.URL.Path+"/", "/primary/"):
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Header: http.Header{}}, nil
+		case strings.HasPrefix(req.URL.Path+"/", "/slow-replica/"):
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		default:
+			body := "<ListBucketResult><Name>last-replica</Name></ListBucketResult>"
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+		}
+	})}
+	cfg := Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		NoCrunchErr:   true,
+		Failover: []FailoverConfig{{
+			Bucket: "primary",
+			Replicas: []ReplicaConfig{
+				{Bucket: "slow-replica", Region: "us-west-2"},
+				{Bucket: "last-replica", Region: "eu-west-1"},
+			},
+			TimeoutMilliseconds: 50,
+		}},
+	}
+	app, err := New(ctx, zap.NewNop(), zap.NewAtomicLevel(), cfg, WithUpstreamHttpClient(client))
+	require.NoError(t, err)
+
+	resp, _, err := app.NewSession().DoRequest(newTestS3Request(http.MethodGet, "/primary/key"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "last-replica", resp.Header.Get(ReplicaHeader))
+	assert.NotEmpty(t, body)
+	require.Len(t, upstreamRequests, 3)
+	assert.Equal(t, "/primary/key", upstreamRequests[0].URL.Path)
+	assert.Equal(t, "/slow-replica/key", upstreamRequests[1].URL.Path)
+	assert.Contains(t, upstreamRequests[1].Header.Get("Authorization"), "/us-west-2/s3/aws4_request")
+	assert.Equal(t, "/last-replica/key", upstreamRequests[2].URL.Path)
+	assert.Equal(t, "s3.eu-west-1.amazonaws.com", upstreamRequests[2].Host)
+	assert.Contains(t, upstreamRequests[2].Header.Get("Authorization"), "/eu-west-1/s3/aws4_request")
+
+	// listings served by a replica keep the name of the primary bucket
+	resp, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodGet, "/primary?list-type=2"))
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "<ListBucketResult><Name>primary</Name></ListBucketResult>", string(body))
+
+	// writes are not failed over
+	upstreamRequests = nil
+	resp, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodPut, "/primary/key"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(ReplicaHeader))
+	assert.Len(t, upstreamRequests, 1)
+
+	assert.Error(t, Config{CloudPlatform: AwsCloudPlatform.String(), Failover: []FailoverConfig{{Bucket: "primary"}}}.Validate())
+}
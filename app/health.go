@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/project-n-oss/sidekick/pkg/shutdown"
+	"golang.org/x/oauth2"
+)
+
+// healthCheckTimeout bounds the duration of each readiness check.
+const healthCheckTimeout = 5 * time.Second
+
+// CheckResult is the result of a single readiness check.
+type CheckResult struct {
+	Name     string
+	Ok       bool
+	Error    string `json:",omitempty"`
+	Duration string
+}
+
+// ReadinessReport details the readiness checks of the app.
+type ReadinessReport struct {
+	Ready  bool
+	Checks []CheckResult
+}
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// Live returns an error if the app is not able to serve requests anymore and should be restarted.
+func (a *App) Live(ctx context.Context) error {
+	return nil
+}
+
+// Ready checks if the app can serve requests: upstream credentials are valid, the upstream is reachable
+// and the app is not shutting down. Checks run concurrently.
+func (a *App) Ready(ctx context.Context) ReadinessReport {
+	checks := a.readinessChecks()
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			begin := time.Now()
+			err := check.check(checkCtx)
+			results[i] = CheckResult{
+				Name:     check.name,
+				Ok:       err == nil,
+				Duration: time.Since(begin).String(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	ret := ReadinessReport{
+		Ready:  true,
+		Checks: results,
+	}
+	for _, result := range results {
+		if !result.Ok {
+			ret.Ready = false
+		}
+	}
+	return ret
+}
+
+func (a *App) readinessChecks() []healthCheck {
+	checks := []healthCheck{{
+		name: "terminating",
+		check: func(ctx context.Context) error {
+			if shutdown.Terminating.Load() {
+				return fmt.Errorf("sidekick is shutting down")
+			}
+			return nil
+		},
+	}}
+	// not ready as soon as shutdown starts, no need to check the upstream
+	if shutdown.Terminating.Load() {
+		return checks
+	}
+
+	cfg := a.Config()
+	switch cfg.CloudPlatform {
+	case AwsCloudPlatform.String():
+		regions := a.readinessRegions(cfg)
+		for _, region := range regions {
+			region := region
+			// the default chain, if some buckets fall back to it, and the credentials configured for buckets
+			if cfg.usesDefaultCredentials() {
+				checks = append(checks, healthCheck{
+					name: "credentials/" + region,
+					check: func(ctx context.Context) error {
+						return a.awsRegistry.CheckCredentials(ctx, sidekickAws.DefaultCredentialsSource, region)
+					},
+				})
+			}
+			for i, credentials := range cfg.Credentials {
+				source := credentials.source()
+				checks = append(checks, healthCheck{
+					name: fmt.Sprintf("credentials/%d/%s", i, region),
+					check: func(ctx context.Context) error {
+						return a.awsRegistry.CheckCredentials(ctx, source, region)
+					},
+				})
+			}
+			checks = append(checks, healthCheck{
+				name: "upstream/" + region,
+				check: func(ctx context.Context) error {
+					return sidekickAws.CheckUpstream(ctx, a.upstreamClient(), region)
+				},
+			})
+		}
+
+	case GcpCloudPlatform.String():
+		checks = append(checks, healthCheck{
+			name: "gcp-token",
+			check: func(ctx context.Context) error {
+				token, err := tokenWithContext(ctx, a.gcpTokenSource)
+				if err != nil {
+					return fmt.Errorf("could not get gcp token: %w", err)
+				}
+				if !token.Valid() {
+					return fmt.Errorf("gcp token is not valid")
+				}
+				return nil
+			},
+		})
+	}
+
+	return checks
+}
+
+// readinessRegions returns the aws regions checked for readiness: the configured Regions, or else the regions
+// of routes, replicas and requests so far. Before any traffic, without routes nor replicas, the region of the
+// environment is checked, so that readiness does not pass without checking anything.
+func (a *App) readinessRegions(cfg Config) []string {
+	if len(cfg.Regions) > 0 {
+		return cfg.Regions
+	}
+
+	seen := map[string]bool{}
+	var ret []string
+	add := func(region string) {
+		if region != "" && !seen[region] {
+			seen[region] = true
+			ret = append(ret, region)
+		}
+	}
+	for _, route := range cfg.Routes {
+		add(route.TargetRegion)
+	}
+	for _, failover := range cfg.Failover {
+		for _, replica := range failover.Replicas {
+			add(replica.Region)
+		}
+	}
+	for _, region := range a.awsRegistry.Regions() {
+		add(region)
+	}
+	if len(ret) == 0 {
+		add(sidekickAws.EnvRegion())
+	}
+	return ret
+}
+
+// tokenWithContext returns a token of ts, or an error once ctx is done. oauth2 token sources do not take a context,
+// a hung metadata server would otherwise block the readiness check.
+func tokenWithContext(ctx context.Context, ts oauth2.TokenSource) (*oauth2.Token, error) {
+	type result struct {
+		token *oauth2.Token
+		err   error
+	}
+	// buffered, so that the goroutine can exit after ctx is done
+	results := make(chan result, 1)
+	go func() {
+		token, err := ts.Token()
+		results <- result{token: token, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-results:
+		return r.token, r.err
+	}
+}
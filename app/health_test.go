@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/project-n-oss/sidekick/pkg/config"
+	"github.com/project-n-oss/sidekick/pkg/shutdown"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestApp_Ready(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "foobar_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "foobar_secret")
+
+	ctx := context.Background()
+	app := &App{
+		awsRegistry: sidekickAws.NewRegistry(),
+		upstreamHttpClient: &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "s3.us-east-1.amazonaws.com", req.URL.Host)
+				return &http.Response{StatusCode: http.StatusForbidden, Body: http.NoBody}, nil
+			}),
+		},
+	}
+	app.cfg.Store(&Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		Regions:       []string{"us-east-1"},
+	})
+
+	report := app.Ready(ctx)
+	require.Len(t, report.Checks, 3)
+	assert.Equal(t, "terminating", report.Checks[0].Name)
+	assert.True(t, report.Checks[0].Ok)
+	assert.Equal(t, "credentials/us-east-1", report.Checks[1].Name)
+	assert.True(t, report.Checks[1].Ok)
+	assert.Equal(t, "upstream/us-east-1", report.Checks[2].Name)
+	assert.True(t, report.Checks[2].Ok)
+	assert.True(t, report.Ready)
+
+	// credentials configured for buckets are checked too
+	app.cfg.Store(&Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		Regions:       []string{"us-east-1"},
+		Credentials: []CredentialsConfig{
+			{Buckets: []string{"static"}, AccessKeyId: config.NewSecretValue("key"), SecretAccessKey: config.NewSecretValue("secret")},
+			{Buckets: []string{"broken"}, CredentialProcess: "exit 1"},
+		},
+	})
+	report = app.Ready(ctx)
+	require.Len(t, report.Checks, 5)
+	assert.Equal(t, "credentials/0/us-east-1", report.Checks[2].Name)
+	assert.True(t, report.Checks[2].Ok)
+	assert.Equal(t, "credentials/1/us-east-1", report.Checks[3].Name)
+	assert.False(t, report.Checks[3].Ok)
+	assert.False(t, report.Ready)
+
+	// the default chain is not checked when no bucket falls back to it
+	app.cfg.Store(&Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		Regions:       []string{"us-east-1"},
+		Credentials: []CredentialsConfig{
+			{Buckets: []string{"*"}, AccessKeyId: config.NewSecretValue("key"), SecretAccessKey: config.NewSecretValue("secret")},
+		},
+	})
+	report = app.Ready(ctx)
+	require.Len(t, report.Checks, 3)
+	assert.Equal(t, "credentials/0/us-east-1", report.Checks[1].Name)
+	assert.True(t, report.Ready)
+
+	shutdown.Terminating.Store(true)
+	defer shutdown.Terminating.Store(false)
+
+	report = app.Ready(ctx)
+	assert.False(t, report.Ready)
+	require.Len(t, report.Checks, 1)
+	assert.False(t, report.Checks[0].Ok)
+}
+
+func TestApp_ReadinessRegions(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-central-1")
+
+	app := &App{awsRegistry: sidekickAws.NewRegistry()}
+	tcs := map[string]struct {
+		cfg      Config
+		expected []string
+	}{
+		"Configured": {
+			cfg:      Config{Regions: []string{"us-east-1"}, Routes: []RouteConfig{{TargetRegion: "eu-west-1"}}},
+			expected: []string{"us-east-1"},
+		},
+		"RoutesAndReplicas": {
+			cfg: Config{
+				Routes:   []RouteConfig{{TargetRegion: "eu-west-1"}, {}},
+				Failover: []FailoverConfig{{Replicas: []ReplicaConfig{{Region: "us-west-2"}, {Region: "eu-west-1"}}}},
+			},
+			expected: []string{"eu-west-1", "us-west-2"},
+		},
+		"Environment": {
+			expected: []string{"eu-central-1"},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, app.readinessRegions(tc.cfg))
+		})
+	}
+}
+
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+func TestApp_ReadyGcpTokenTimeout(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+	app := &App{
+		gcpTokenSource: tokenSourceFunc(func() (*oauth2.Token, error) {
+			<-hung
+			return nil, fmt.Errorf("unreachable")
+		}),
+	}
+	app.cfg.Store(&Config{CloudPlatform: GcpCloudPlatform.String()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := app.Ready(ctx)
+	assert.False(t, report.Ready)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, "gcp-token", report.Checks[1].Name)
+	assert.Contains(t, report.Checks[1].Error, context.DeadlineExceeded.Error())
+}
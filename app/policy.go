@@ -0,0 +1,278 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"go.uber.org/zap"
+)
+
+type PolicyEffect string
+
+const (
+	AllowPolicyEffect PolicyEffect = "Allow"
+	DenyPolicyEffect  PolicyEffect = "Deny"
+)
+
+// PolicyConfig restricts the requests sidekick forwards upstream.
+// Requests matching a Deny rule are denied. If there are Allow rules, requests must also match one of them.
+type PolicyConfig struct {
+	// DryRun logs the requests the policy would deny instead of denying them.
+	DryRun bool         `yaml:"DryRun"`
+	Rules  []PolicyRule `yaml:"Rules"`
+}
+
+// PolicyRule matches requests on every non empty field.
+type PolicyRule struct {
+	Effect PolicyEffect `yaml:"Effect"`
+	// Buckets are bucket names or globs, e.g. "logs-*".
+	Buckets []string `yaml:"Buckets"`
+	// Keys are object key globs, where * also matches /, e.g. "datasets/*.parquet".
+	// List requests are matched with their prefix parameter. Deny rules also match listings that could return
+	// matching keys, i.e. whose prefix is shorter than the pattern, e.g. "" or "data" for "datasets/*.parquet".
+	Keys []string `yaml:"Keys"`
+	// Operations are S3 api operations or globs, e.g. "GetObject" or "List*".
+	Operations []string `yaml:"Operations"`
+	// Identities are the common names or URI SANs of verified client certificates, e.g. spiffe ids.
+	// Requests received without a verified client certificate never match.
+	Identities []string `yaml:"Identities"`
+	// SourceIps are client ips or CIDRs, e.g. "10.0.0.0/8".
+	SourceIps []string `yaml:"SourceIps"`
+}
+
+func (r PolicyRule) Validate() error {
+	if r.Effect != AllowPolicyEffect && r.Effect != DenyPolicyEffect {
+		return fmt.Errorf("Effect must be one of: %s, %s", AllowPolicyEffect, DenyPolicyEffect)
+	}
+	for _, bucket := range r.Buckets {
+		if _, err := path.Match(bucket, ""); err != nil {
+			return fmt.Errorf("invalid bucket pattern %q: %w", bucket, err)
+		}
+	}
+	for _, sourceIp := range r.SourceIps {
+		if _, err := parseSourceIp(sourceIp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyRequest is what policy rules match requests on.
+type policyRequest struct {
+	bucket     string
+	key        string
+	operation  string
+	identities []string
+	sourceIp   net.IP
+	// list is true if key is the prefix of a list request
+	list bool
+}
+
+func newPolicyRequest(req *http.Request, sourceBucket sidekickAws.SourceBucket, operation string) policyRequest {
+	ret := policyRequest{
+		bucket:    sourceBucket.Bucket,
+		key:       sourceBucket.Key,
+		operation: operation,
+	}
+	if ret.key == "" && strings.HasPrefix(operation, "List") {
+		ret.key = req.URL.Query().Get("prefix")
+		ret.list = true
+	}
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+		cert := req.TLS.VerifiedChains[0][0]
+		ret.identities = append(ret.identities, cert.Subject.CommonName)
+		for _, uri := range cert.URIs {
+			ret.identities = append(ret.identities, uri.String())
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ret.sourceIp = net.ParseIP(host)
+	return ret
+}
+
+func (r PolicyRule) matches(req policyRequest) bool {
+	if len(r.Buckets) > 0 && !matchesAny(r.Buckets, req.bucket, func(pattern, s string) bool {
+		ok, _ := path.Match(pattern, s)
+		return ok
+	}) {
+		return false
+	}
+	matchKey := matchGlob
+	// a prefix is denied if any key it lists could be
+	if req.list && r.Effect == DenyPolicyEffect {
+		matchKey = matchGlobPrefix
+	}
+	if len(r.Keys) > 0 && !matchesAny(r.Keys, req.key, matchKey) {
+		return false
+	}
+	if len(r.Operations) > 0 && !matchesAny(r.Operations, req.operation, matchGlob) {
+		return false
+	}
+	if len(r.Identities) > 0 {
+		matched := false
+		for _, identity := range req.identities {
+			if matchesAny(r.Identities, identity, matchGlob) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.SourceIps) > 0 {
+		matched := false
+		for _, sourceIp := range r.SourceIps {
+			if ipNet, err := parseSourceIp(sourceIp); err == nil && req.sourceIp != nil && ipNet.Contains(req.sourceIp) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluate returns whether the policy allows req, and the index of the rule that decided it, -1 if no rule did.
+func (c PolicyConfig) evaluate(req policyRequest) (bool, int) {
+	hasAllowRules := false
+	allowRule := -1
+	for i, rule := range c.Rules {
+		if rule.Effect == AllowPolicyEffect {
+			hasAllowRules = true
+		}
+		if !rule.matches(req) {
+			continue
+		}
+		// deny rules take precedence over allow rules
+		if rule.Effect == DenyPolicyEffect {
+			return false, i
+		}
+		if allowRule == -1 {
+			allowRule = i
+		}
+	}
+	if hasAllowRules && allowRule == -1 {
+		return false, -1
+	}
+	return true, allowRule
+}
+
+// checkPolicy returns an ErrAccessDenied error if the policy denies req. In dry run, denials are only logged.
+func (sess *Session) checkPolicy(req *http.Request, sourceBucket sidekickAws.SourceBucket, operation string) error {
+	policy := sess.cfg.Policy
+	if len(policy.Rules) == 0 {
+		return nil
+	}
+	policyReq := newPolicyRequest(req, sourceBucket, operation)
+	allowed, rule := policy.evaluate(policyReq)
+	if allowed {
+		return nil
+	}
+
+	reason := "no policy rule allows it"
+	if rule != -1 {
+		reason = fmt.Sprintf("denied by policy rule %d", rule)
+	}
+	sess.app.policyDenials.Inc(fmt.Sprint(policy.DryRun))
+	if policy.DryRun {
+		sess.Logger().Warn("request would be denied by policy",
+			zap.String("operation", policyReq.operation),
+			zap.String("bucket", policyReq.bucket),
+			zap.String("key", policyReq.key),
+			zap.String("reason", reason),
+		)
+		return nil
+	}
+	return newError(AuthErrorKind, fmt.Errorf("%w: %s %s/%s %s", ErrAccessDenied, policyReq.operation, policyReq.bucket, policyReq.key, reason))
+}
+
+func matchesAny(patterns []string, s string, match func(pattern, s string) bool) bool {
+	for _, pattern := range patterns {
+		if match(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether s matches pattern, where * matches any sequence of characters and ? any single character.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		default:
+			if s == "" || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
+
+// matchGlobPrefix reports whether some string starting with prefix matches pattern.
+func matchGlobPrefix(pattern, prefix string) bool {
+	for len(pattern) > 0 {
+		if prefix == "" {
+			return true
+		}
+		switch pattern[0] {
+		case '*':
+			// * matches the rest of the prefix
+			return true
+		case '?':
+			pattern, prefix = pattern[1:], prefix[1:]
+		default:
+			if pattern[0] != prefix[0] {
+				return false
+			}
+			pattern, prefix = pattern[1:], prefix[1:]
+		}
+	}
+	return prefix == ""
+}
+
+// parseSourceIp parses an ip or a CIDR.
+func parseSourceIp(sourceIp string) (*net.IPNet, error) {
+	if !strings.Contains(sourceIp, "/") {
+		ip := net.ParseIP(sourceIp)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source ip %q", sourceIp)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(sourceIp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source ip %q: %w", sourceIp, err)
+	}
+	return ipNet, nil
+}
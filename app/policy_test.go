@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestS3Request(method, target string) *http.Request {
+	req := httptest.NewRequest(method, "http://localhost:7075"+target, nil)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIA/20230511/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=0")
+	req.RemoteAddr = "10.1.2.3:51234"
+	return req
+}
+
+func TestApp_Policy(t *testing.T) {
+	policy := PolicyConfig{Rules: []PolicyRule{
+		{Effect: AllowPolicyEffect, Buckets: []string{"datasets-*"}, Keys: []string{"public/*"}, Operations: []string{"GetObject", "HeadObject", "List*"}},
+		{Effect: AllowPolicyEffect, Buckets: []string{"datasets-*"}, SourceIps: []string{"10.0.0.0/8"}, Operations: []string{"PutObject"}},
+		{Effect: AllowPolicyEffect, Buckets: []string{"datasets-*"}, Identities: []string{"spiffe://cluster/ns/etl/*"}},
+		{Effect: DenyPolicyEffect, Keys: []string{"public/secret/*"}},
+	}}
+	cfg := Config{CloudPlatform: AwsCloudPlatform.String(), Policy: policy}
+	require.NoError(t, cfg.Validate())
+
+	etlCert := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "cluster", Path: "/ns/etl/sa/default"}}}
+	for name, tc := range map[string]struct {
+		method   string
+		target   string
+		remote   string
+		cert     *x509.Certificate
+		expected bool
+	}{
+		"GetPublicObject":     {method: http.MethodGet, target: "/datasets-eu/public/a/b.csv", expected: true},
+		"GetPrivateObject":    {method: http.MethodGet, target: "/datasets-eu/private/b.csv"},
+		"ListPublicPrefix":    {method: http.MethodGet, target: "/datasets-eu?list-type=2&prefix=public/a", expected: true},
+		"ListRoot":            {method: http.MethodGet, target: "/datasets-eu?list-type=2"},
+		"OtherBucket":         {method: http.MethodGet, target: "/logs/public/a"},
+		"PutFromPrivateRange": {method: http.MethodPut, target: "/datasets-eu/private/b.csv", expected: true},
+		"PutFromOutside":      {method: http.MethodPut, target: "/datasets-eu/private/b.csv", remote: "192.168.1.1:1234"},
+		"DeleteFromEtl":       {method: http.MethodDelete, target: "/datasets-eu/private/b.csv", cert: etlCert, expected: true},
+		"DenyWins":            {method: http.MethodGet, target: "/datasets-eu/public/secret/key"},
+		"ListDeniedPrefix":    {method: http.MethodGet, target: "/datasets-eu?list-type=2&prefix=public/secret/a"},
+		"ListDeniedAncestor":  {method: http.MethodGet, target: "/datasets-eu?list-type=2&prefix=public/"},
+		"ListDeniedShorter":   {method: http.MethodGet, target: "/datasets-eu?list-type=2&prefix=public/se"},
+		"ListSiblingPrefix":   {method: http.MethodGet, target: "/datasets-eu?list-type=2&prefix=public/secrets", expected: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := newTestS3Request(tc.method, tc.target)
+			if tc.remote != "" {
+				req.RemoteAddr = tc.remote
+			}
+			if tc.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}
+			}
+			sourceBucket := mustSourceBucket(t, req)
+			allowed, _ := policy.evaluate(newPolicyRequest(req, sourceBucket, sidekickAws.Operation(req, sourceBucket)))
+			assert.Equal(t, tc.expected, allowed)
+		})
+	}
+
+	for name, rule := range map[string]PolicyRule{
+		"BadEffect":   {Effect: "Maybe"},
+		"BadPattern":  {Effect: DenyPolicyEffect, Buckets: []string{"logs-["}},
+		"BadSourceIp": {Effect: DenyPolicyEffect, SourceIps: []string{"10.0.0.0/33"}},
+	} {
+		cfg := Config{CloudPlatform: AwsCloudPlatform.String(), Policy: PolicyConfig{Rules: []PolicyRule{rule}}}
+		assert.Error(t, cfg.Validate(), name)
+	}
+}
+
+func TestApp_PolicyDenied(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "foobar_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "foobar_secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	upstreamCalls := 0
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		upstreamCalls++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}}, nil
+	})}
+	cfg := Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		NoCrunchErr:   true,
+		Policy:        PolicyConfig{Rules: []PolicyRule{{Effect: DenyPolicyEffect, Operations: []string{"DeleteObject"}}}},
+	}
+	app, err := New(ctx, zap.NewNop(), zap.NewAtomicLevel(), cfg, WithUpstreamHttpClient(client))
+	require.NoError(t, err)
+
+	_, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodDelete, "/bucket/key"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAccessDenied))
+	assert.Equal(t, AuthErrorKind, KindOf(err))
+	assert.Equal(t, 0, upstreamCalls)
+
+	resp, _, err := app.NewSession().DoRequest(newTestS3Request(http.MethodGet, "/bucket/key"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, upstreamCalls)
+
+	// dry run only logs and counts denials
+	cfg.Policy.DryRun = true
+	require.NoError(t, app.Reload(cfg))
+	resp, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodDelete, "/bucket/key"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 2, upstreamCalls)
+	assert.Equal(t, float64(1), app.policyDenials.Value("false"))
+	assert.Equal(t, float64(1), app.policyDenials.Value("true"))
+}
+
+func mustSourceBucket(t *testing.T, req *http.Request) sidekickAws.SourceBucket {
+	sourceBucket, err := sidekickAws.ExtractSourceBucket(req)
+	require.NoError(t, err)
+	return sourceBucket
+}
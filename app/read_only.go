@@ -0,0 +1,16 @@
+package app
+
+import (
+	"fmt"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+)
+
+// checkReadOnly returns an ErrReadOnly error if operation modifies a read-only bucket.
+func (sess *Session) checkReadOnly(sourceBucket sidekickAws.SourceBucket, operation string) error {
+	if sidekickAws.IsReadOperation(operation) || !sess.cfg.IsReadOnly(sourceBucket.Bucket) {
+		return nil
+	}
+	sess.app.readOnlyRejections.Inc(operation)
+	return newError(AuthErrorKind, fmt.Errorf("%w: %s is not allowed on %s", ErrReadOnly, operation, sourceBucket.Bucket))
+}
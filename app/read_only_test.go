@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestApp_ReadOnly(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "foobar_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "foobar_secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	upstreamCalls := 0
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		upstreamCalls++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}}, nil
+	})}
+	cfg := Config{
+		CloudPlatform:   AwsCloudPlatform.String(),
+		NoCrunchErr:     true,
+		ReadOnlyBuckets: []string{"analytics-*"},
+	}
+	app, err := New(ctx, zap.NewNop(), zap.NewAtomicLevel(), cfg, WithUpstreamHttpClient(client))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		method     string
+		target     string
+		copySource string
+		readOnly   bool
+	}{
+		{method: http.MethodGet, target: "/analytics-eu/key"},
+		{method: http.MethodGet, target: "/analytics-eu?list-type=2"},
+		{method: http.MethodPut, target: "/analytics-eu/key", readOnly: true},
+		{method: http.MethodPut, target: "/analytics-eu/key", copySource: "/other/key", readOnly: true},
+		{method: http.MethodDelete, target: "/analytics-eu/key", readOnly: true},
+		{method: http.MethodPost, target: "/analytics-eu?delete", readOnly: true},
+		{method: http.MethodPost, target: "/analytics-eu/key?uploads", readOnly: true},
+		{method: http.MethodPut, target: "/analytics-eu/key?tagging", readOnly: true},
+		{method: http.MethodPut, target: "/analytics-eu/key?acl", readOnly: true},
+		{method: http.MethodPut, target: "/scratch/key"},
+	} {
+		req := newTestS3Request(tc.method, tc.target)
+		if tc.copySource != "" {
+			req.Header.Set("X-Amz-Copy-Source", tc.copySource)
+		}
+		before := upstreamCalls
+		resp, _, err := app.NewSession().DoRequest(req)
+		if tc.readOnly {
+			require.Error(t, err, tc.target)
+			assert.True(t, errors.Is(err, ErrReadOnly))
+			assert.Equal(t, AuthErrorKind, KindOf(err))
+			assert.Equal(t, before, upstreamCalls, "read-only requests must not reach the upstream")
+			continue
+		}
+		require.NoError(t, err, tc.target)
+		resp.Body.Close()
+		assert.Equal(t, before+1, upstreamCalls)
+	}
+	assert.Equal(t, float64(1), app.readOnlyRejections.Value("CopyObject"))
+	assert.Equal(t, float64(1), app.readOnlyRejections.Value("DeleteObjects"))
+
+	// writes routed to a read-only bucket are rejected too
+	cfg.Routes = []RouteConfig{{Bucket: "scratch", Prefix: "archive/", TargetBucket: "analytics-archive"}}
+	require.NoError(t, app.Reload(cfg))
+	before := upstreamCalls
+	_, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodPut, "/scratch/archive/key"))
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.Equal(t, before, upstreamCalls)
+	resp, _, err := app.NewSession().DoRequest(newTestS3Request(http.MethodGet, "/scratch/archive/key"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// the global switch applies to every bucket
+	cfg.ReadOnly = true
+	require.NoError(t, app.Reload(cfg))
+	_, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodPut, "/scratch/key"))
+	assert.True(t, errors.Is(err, ErrReadOnly))
+}
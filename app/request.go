@@ -1,71 +1,132 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	sidekickLogger "github.com/project-n-oss/sidekick/pkg/logger"
+	"go.uber.org/zap"
 )
 
 // DoRequest makes a request to the cloud platform
 // Does a request to the source bucket and if it returns 404, tries the crunched bucket
 // Returns the response and a boolean indicating if the response is from the crunched bucket
 func (sess *Session) DoRequest(req *http.Request) (*http.Response, bool, error) {
-	switch sess.app.cfg.CloudPlatform {
+	switch sess.cfg.CloudPlatform {
 	case AwsCloudPlatform.String():
 		return sess.DoAwsRequest(req)
 	default:
-		return nil, false, fmt.Errorf("CloudPlatform %s not supported", sess.app.cfg.CloudPlatform)
+		return nil, false, fmt.Errorf("%w: %s", ErrUnsupportedCloudPlatform, sess.cfg.CloudPlatform)
 	}
 }
 
-const crunchFileFoundErrStatus = "409 Src file not found, but crunched file also found"
-const crunchFileFoundStatusCode = 409
-
 // DoAwsRequest makes a request to AWS
-// If a crunched version of the source file exists, returns a 500 response
+// If a crunched version of the source file exists, returns ErrCrunchLocked
 // Returns the response and a boolean indicating if a crunched file was found
 // You can disable this behavior by setting NoCrunchErr to true in the config
 func (sess *Session) DoAwsRequest(req *http.Request) (*http.Response, bool, error) {
 	sourceBucket, err := sidekickAws.ExtractSourceBucket(req)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to extract source bucket from request: %w", err)
+		return nil, false, sourceBucketError(err)
 	}
-
-	cloudRequest, err := sidekickAws.NewRequest(sess.Context(), sess.Logger(), req, sourceBucket)
+	sess.WithLogOverride(sidekickLogger.BucketOverride, sourceBucket.Bucket)
+	operation := sidekickAws.Operation(req, sourceBucket)
+	if err := sess.checkReadOnly(sourceBucket, operation); err != nil {
+		return nil, false, err
+	}
+	if err := sess.checkPolicy(req, sourceBucket, operation); err != nil {
+		return nil, false, err
+	}
+	// policies apply to the logical bucket, the rest of the request to the physical one, read-only to both
+	req, sourceBucket, route, err := sess.cfg.routeRequest(req, sourceBucket, operation)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to make aws request: %w", err)
+		return nil, false, err
+	}
+	if route != nil {
+		sess.WithLogger(sess.Logger().With(zap.String("upstreamBucket", sourceBucket.Bucket), zap.String("upstreamKey", sourceBucket.Key)))
+		// a route must not open a write path to a read-only bucket
+		if err := sess.checkReadOnly(sourceBucket, operation); err != nil {
+			return nil, false, err
+		}
 	}
 
-	resp, err := http.DefaultClient.Do(cloudRequest)
+	primaryBucket := sourceBucket.Bucket
+	upstream, err := sess.doAwsRequestWithFailover(req, sourceBucket, operation)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to do aws request: %w", err)
+		return nil, false, err
+	}
+	cloudRequest, resp, sourceBucket, credentialsSource := upstream.cloudRequest, upstream.resp, upstream.sourceBucket, upstream.credentialsSource
+	// log the upstream request ids next to the sidekick one, so that failures can be traced with aws support
+	sess.WithLogger(sess.Logger().With(
+		zap.String("awsRequestId", resp.Header.Get("X-Amz-Request-Id")),
+		zap.String("awsId2", resp.Header.Get("X-Amz-Id-2")),
+	))
+	if isListOperation(operation) && resp.StatusCode == http.StatusOK {
+		urlEncoded := req.URL.Query().Get("encoding-type") == "url"
+		// replicas are listed under the name of the primary bucket, itself listed under its logical name
+		if upstream.replica != nil {
+			replicaRoute := RouteConfig{Bucket: primaryBucket, TargetBucket: upstream.replica.Bucket}
+			if err := replicaRoute.rewriteListResponse(resp, urlEncoded); err != nil {
+				return nil, false, err
+			}
+		}
+		if route != nil {
+			if err := route.rewriteListResponse(resp, urlEncoded); err != nil {
+				return nil, false, err
+			}
+		}
 	}
 
 	// if the source file is not already a crunched file, check if the crunched file exists
-	if !sess.app.cfg.NoCrunchErr && !isCrunchedFile(cloudRequest.URL.Path) {
+	if !sess.cfg.NoCrunchErr && !isCrunchedFile(cloudRequest.URL.Path) {
 		objectKey := makeCrunchFilePath(sourceBucket.Bucket, cloudRequest.URL.Path)
 
-		s3Client, err := sidekickAws.GetS3ClientFromRegion(sess.Context(), sourceBucket.Region)
+		s3Client, err := sess.app.awsRegistry.S3Client(sess.Context(), credentialsSource, sourceBucket.Region)
 		if err != nil {
+			resp.Body.Close()
 			return nil, false, fmt.Errorf("failed to get s3 client for region '%s': %w", sourceBucket.Region, err)
 		}
 
 		// ignore errors, we only want to check if the object exists
+		headBegin := time.Now()
 		headResp, _ := s3Client.HeadObject(sess.Context(), &s3.HeadObjectInput{
 			Bucket: aws.String(sourceBucket.Bucket),
 			Key:    aws.String(objectKey),
 		})
+		sess.timings.Set(func(t *Timings) { t.CrunchHead = time.Since(headBegin) })
 
-		// found crunched file, return 500 to client
+		// found crunched file, return an error to the client
 		if headResp != nil && headResp.ETag != nil {
-			resp.StatusCode = crunchFileFoundStatusCode
-			resp.Status = crunchFileFoundErrStatus
+			resp.Body.Close()
+			return nil, true, newError(ClientErrorKind, ErrCrunchLocked)
 		}
 		return resp, true, nil
 	}
 
 	return resp, false, err
 }
+
+// sendAwsRequest signs req for sourceBucket with the credentials of credentialsSource and sends it upstream with ctx.
+func (sess *Session) sendAwsRequest(ctx context.Context, req *http.Request, sourceBucket sidekickAws.SourceBucket, credentialsSource sidekickAws.CredentialsSource) (*http.Request, *http.Response, error) {
+	var credentialsDuration time.Duration
+	cloudRequest, err := sess.app.awsRegistry.NewRequest(ctx, sess.Logger(), req, sourceBucket,
+		sidekickAws.WithCredentialsDuration(&credentialsDuration),
+		sidekickAws.WithCredentialsSource(credentialsSource),
+	)
+	sess.timings.Set(func(t *Timings) { t.CredentialLookup += credentialsDuration })
+	if err != nil {
+		return nil, nil, signRequestError(err)
+	}
+
+	cloudRequest = cloudRequest.WithContext(sess.timings.withUpstreamTrace(cloudRequest.Context()))
+	resp, err := sess.app.upstreamClient().Do(cloudRequest)
+	if err != nil {
+		return nil, nil, upstreamError(err)
+	}
+	return cloudRequest, resp, nil
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/project-n-oss/sidekick/pkg/copybody"
+)
+
+// RouteConfig maps a logical bucket, or a key prefix of it, to the physical bucket, prefix and region it is stored in.
+type RouteConfig struct {
+	Bucket string `yaml:"Bucket"`
+	// Prefix restricts the route to the keys starting with it, e.g. "datasets/".
+	Prefix       string `yaml:"Prefix"`
+	TargetBucket string `yaml:"TargetBucket"`
+	// TargetPrefix replaces Prefix in keys.
+	TargetPrefix string `yaml:"TargetPrefix"`
+	// TargetRegion is the region of TargetBucket, requests are signed for it. Defaults to the region of the request.
+	TargetRegion string `yaml:"TargetRegion"`
+}
+
+func (r RouteConfig) Validate() error {
+	if r.Bucket == "" || r.TargetBucket == "" {
+		return fmt.Errorf("Bucket and TargetBucket must be set")
+	}
+	return nil
+}
+
+// physicalKey returns the key a logical key is stored at.
+func (r RouteConfig) physicalKey(key string) string {
+	return r.TargetPrefix + strings.TrimPrefix(key, r.Prefix)
+}
+
+// logicalKey returns the key clients know a physical key as.
+func (r RouteConfig) logicalKey(key string) string {
+	if !strings.HasPrefix(key, r.TargetPrefix) {
+		return key
+	}
+	return r.Prefix + strings.TrimPrefix(key, r.TargetPrefix)
+}
+
+// Route returns the route of key in bucket. If several routes match, the one with the longest prefix is used.
+func (c Config) Route(bucket, key string) (RouteConfig, bool) {
+	ret, found := RouteConfig{}, false
+	for _, route := range c.Routes {
+		if route.Bucket != bucket || !strings.HasPrefix(key, route.Prefix) {
+			continue
+		}
+		if !found || len(route.Prefix) > len(ret.Prefix) {
+			ret, found = route, true
+		}
+	}
+	return ret, found
+}
+
+// isListOperation returns true for the operations whose prefix parameter and response keys are routed.
+func isListOperation(operation string) bool {
+	return operation == "ListObjectsV2" || operation == "ListObjects"
+}
+
+// listKeyParameters are the query parameters of list requests holding keys.
+var listKeyParameters = []string{"prefix", "start-after", "marker"}
+
+// routeRequest rewrites req for the physical bucket, key and region of the route matching it, and the copy
+// source header for the route of the copied object. It returns the route of req, if any.
+// req is not modified, a clone is returned if anything changes.
+func (c Config) routeRequest(req *http.Request, sourceBucket sidekickAws.SourceBucket, operation string) (*http.Request, sidekickAws.SourceBucket, *RouteConfig, error) {
+	if len(c.Routes) == 0 {
+		return req, sourceBucket, nil, nil
+	}
+
+	key := sourceBucket.Key
+	if isListOperation(operation) {
+		key = req.URL.Query().Get("prefix")
+	}
+	route, routed := c.Route(sourceBucket.Bucket, key)
+	copySource, copySourceRouted, err := c.routeCopySource(req.Header.Get(sidekickAws.CopySourceHeader))
+	if err != nil {
+		return nil, sidekickAws.SourceBucket{}, nil, err
+	}
+	if !routed && !copySourceRouted {
+		return req, sourceBucket, nil, nil
+	}
+
+	if !routed {
+		clone := req.Clone(req.Context())
+		copybody.CopyReqBody(req, clone)
+		clone.Header.Set(sidekickAws.CopySourceHeader, copySource)
+		return clone, sourceBucket, nil, nil
+	}
+	clone, sourceBucket := route.apply(req, sourceBucket, operation)
+	if copySourceRouted {
+		clone.Header.Set(sidekickAws.CopySourceHeader, copySource)
+	}
+	return clone, sourceBucket, &route, nil
+}
+
+// apply returns a clone of req and sourceBucket for the physical bucket, key and region of r.
+func (r RouteConfig) apply(req *http.Request, sourceBucket sidekickAws.SourceBucket, operation string) (*http.Request, sidekickAws.SourceBucket) {
+	clone := req.Clone(req.Context())
+	copybody.CopyReqBody(req, clone)
+
+	sourceBucket.Bucket = r.TargetBucket
+	if r.TargetRegion != "" {
+		sourceBucket.Region = r.TargetRegion
+	}
+	if sourceBucket.Key != "" {
+		sourceBucket.Key = r.physicalKey(sourceBucket.Key)
+	}
+	if isListOperation(operation) {
+		query := req.URL.Query()
+		for _, name := range listKeyParameters {
+			if query.Has(name) || name == "prefix" {
+				query.Set(name, r.physicalKey(query.Get(name)))
+			}
+		}
+		clone.URL.RawQuery = query.Encode()
+	}
+
+	clone.URL.Path = "/" + sourceBucket.Key
+	if sourceBucket.Style != sidekickAws.VirtualHostedStyle {
+		clone.URL.Path = "/" + sourceBucket.Bucket
+		if sourceBucket.Key != "" {
+			clone.URL.Path += "/" + sourceBucket.Key
+		}
+	}
+	clone.URL.RawPath = ""
+	return clone, sourceBucket
+}
+
+// routeCopySource returns the copy source header of the physical object copySource is routed to, if it is.
+// copySource is formatted as [/]bucket/key[?versionId=id], with an url encoded key.
+func (c Config) routeCopySource(copySource string) (string, bool, error) {
+	if copySource == "" {
+		return "", false, nil
+	}
+	source, version, hasVersion := strings.Cut(strings.TrimPrefix(copySource, "/"), "?")
+	source, err := url.PathUnescape(source)
+	if err != nil {
+		return "", false, newError(ClientErrorKind, fmt.Errorf("invalid %s header: %w", sidekickAws.CopySourceHeader, err))
+	}
+	bucket, key, _ := strings.Cut(source, "/")
+	route, routed := c.Route(bucket, key)
+	if !routed {
+		return "", false, nil
+	}
+
+	ret := (&url.URL{Path: "/" + route.TargetBucket + "/" + route.physicalKey(key)}).EscapedPath()
+	if hasVersion {
+		ret += "?" + version
+	}
+	return ret, true, nil
+}
+
+// listResultElements are the elements of list responses holding the bucket name or keys.
+var listResultElements = regexp.MustCompile(`<(Name|Prefix|Key|StartAfter|Marker|NextMarker)>([^<]*)<`)
+
+// rewriteListResponse rewrites the bucket name and keys of a list response to their logical values.
+// Keys are url encoded in responses to requests with encoding-type=url.
+func (r RouteConfig) rewriteListResponse(resp *http.Response, urlEncoded bool) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return upstreamError(err)
+	}
+
+	body = listResultElements.ReplaceAllFunc(body, func(match []byte) []byte {
+		submatches := listResultElements.FindSubmatch(match)
+		element, value := string(submatches[1]), html.UnescapeString(string(submatches[2]))
+		if element == "Name" {
+			if value == r.TargetBucket {
+				value = r.Bucket
+			}
+		} else {
+			if urlEncoded {
+				if decoded, err := url.QueryUnescape(value); err == nil {
+					value = decoded
+				}
+			}
+			value = r.logicalKey(value)
+			if urlEncoded {
+				value = strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+			}
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("<" + element + ">")
+		xml.EscapeText(&buf, []byte(value))
+		buf.WriteString("<")
+		return buf.Bytes()
+	})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
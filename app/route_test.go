@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestApp_Route(t *testing.T) {
+	cfg := Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		Routes: []RouteConfig{
+			{Bucket: "datasets", TargetBucket: "datasets-v2", TargetRegion: "eu-west-1"},
+			{Bucket: "datasets", Prefix: "raw/", TargetBucket: "raw-archive", TargetPrefix: "datasets/raw/"},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	route, ok := cfg.Route("datasets", "raw/2023/a.csv")
+	require.True(t, ok)
+	assert.Equal(t, "raw-archive", route.TargetBucket)
+	assert.Equal(t, "datasets/raw/2023/a.csv", route.physicalKey("raw/2023/a.csv"))
+	assert.Equal(t, "raw/2023/a.csv", route.logicalKey("datasets/raw/2023/a.csv"))
+	route, ok = cfg.Route("datasets", "curated/a.csv")
+	require.True(t, ok)
+	assert.Equal(t, "datasets-v2", route.TargetBucket)
+	_, ok = cfg.Route("logs", "a.csv")
+	assert.False(t, ok)
+
+	copySource, ok, err := cfg.routeCopySource("/datasets/raw/my%20file.csv?versionId=1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "/raw-archive/datasets/raw/my%20file.csv?versionId=1", copySource)
+
+	assert.Error(t, Config{CloudPlatform: AwsCloudPlatform.String(), Routes: []RouteConfig{{Bucket: "datasets"}}}.Validate())
+}
+
+func TestApp_RouteRequest(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "foobar_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "foobar_secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var upstreamRequests []*http.Request
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		upstreamRequests = append(upstreamRequests, req)
+		switch {
+		case req.Method == http.MethodHead:
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Header: http.Header{}}, nil
+		case req.URL.Query().Has("list-type"):
+			body := `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>raw-archive</Name><Prefix>datasets/raw/</Prefix><KeyCount>2</KeyCount><Contents><Key>datasets/raw/a%26b.csv</Key></Contents><CommonPrefixes><Prefix>datasets/raw/2023/</Prefix></CommonPrefixes></ListBucketResult>`
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}, ContentLength: int64(len(body))}, nil
+		default:
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}}, nil
+		}
+	})}
+	cfg := Config{
+		CloudPlatform: AwsCloudPlatform.String(),
+		Routes: []RouteConfig{
+			{Bucket: "datasets", Prefix: "raw/", TargetBucket: "raw-archive", TargetPrefix: "datasets/raw/", TargetRegion: "eu-west-1"},
+		},
+	}
+	app, err := New(ctx, zap.NewNop(), zap.NewAtomicLevel(), cfg, WithUpstreamHttpClient(client))
+	require.NoError(t, err)
+
+	// objects and their crunched versions are looked up in the physical bucket
+	resp, _, err := app.NewSession().DoRequest(newTestS3Request(http.MethodGet, "/datasets/raw/a.csv"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Len(t, upstreamRequests, 2)
+	assert.Equal(t, "s3.eu-west-1.amazonaws.com", upstreamRequests[0].Host)
+	assert.Equal(t, "/raw-archive/datasets/raw/a.csv", upstreamRequests[0].URL.Path)
+	assert.Contains(t, upstreamRequests[0].Header.Get("Authorization"), "/eu-west-1/s3/aws4_request")
+	assert.Equal(t, "raw-archive.s3.eu-west-1.amazonaws.com", upstreamRequests[1].URL.Host)
+	assert.Equal(t, "/datasets/raw/a.0.gr.csv", upstreamRequests[1].URL.Path)
+
+	// listings are routed by prefix and their keys rewritten back
+	upstreamRequests = nil
+	resp, _, err = app.NewSession().DoRequest(newTestS3Request(http.MethodGet, "/datasets?list-type=2&prefix=raw/&encoding-type=url"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "datasets/raw/", upstreamRequests[0].URL.Query().Get("prefix"))
+	assert.Contains(t, string(body), "<Name>datasets</Name><Prefix>raw%2F</Prefix>")
+	assert.Contains(t, string(body), "<Key>raw%2Fa%26b.csv</Key>")
+	assert.Contains(t, string(body), "<Prefix>raw%2F2023%2F</Prefix>")
+	assert.Equal(t, int64(len(body)), resp.ContentLength)
+
+	// copies read from the physical source
+	upstreamRequests = nil
+	req := newTestS3Request(http.MethodPut, "/scratch/copy.csv")
+	req.Header.Set("X-Amz-Copy-Source", "datasets/raw/a.csv")
+	resp, _, err = app.NewSession().DoRequest(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "/scratch/copy.csv", upstreamRequests[0].URL.Path)
+	assert.Equal(t, "/raw-archive/datasets/raw/a.csv", upstreamRequests[0].Header.Get("X-Amz-Copy-Source"))
+	assert.Equal(t, "datasets/raw/a.csv", req.Header.Get("X-Amz-Copy-Source"))
+}
@@ -3,19 +3,24 @@ package app
 import (
 	"context"
 
+	sidekickLogger "github.com/project-n-oss/sidekick/pkg/logger"
 	"go.uber.org/zap"
 )
 
 type Session struct {
-	app     *App
-	logger  *zap.Logger
-	context context.Context
+	app       *App
+	cfg       Config
+	logger    *zap.Logger
+	context   context.Context
+	requestId string
+	timings   Timings
 }
 
 func (a *App) NewSession() *Session {
 	return &Session{
 		logger:  a.logger,
 		app:     a,
+		cfg:     a.Config(),
 		context: context.Background(),
 	}
 }
@@ -25,10 +30,33 @@ func (s *Session) WithLogger(logger *zap.Logger) *Session {
 	return s
 }
 
+func (s *Session) WithRequestId(requestId string) *Session {
+	s.requestId = requestId
+	return s
+}
+
+// Timings returns the breakdown of the time spent serving the request.
+func (s *Session) Timings() *Timings {
+	return &s.timings
+}
+
+// RequestId returns the id of the request the session serves.
+func (s *Session) RequestId() string {
+	return s.requestId
+}
+
 func (s *Session) Logger() *zap.Logger {
 	return s.logger
 }
 
+// WithLogOverride changes the session logger level if a log override matches value.
+func (s *Session) WithLogOverride(kind sidekickLogger.OverrideKind, value string) *Session {
+	if level, ok := s.app.logOverrides.Lookup(kind, value); ok {
+		s.logger = sidekickLogger.WithLevel(s.logger, level)
+	}
+	return s
+}
+
 func (s *Session) WithContext(ctx context.Context) *Session {
 	s.context = ctx
 	return s
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Timings is the breakdown of the time spent serving a request.
+// Phases that did not happen, e.g. dns and connect for a reused connection, are zero.
+type Timings struct {
+	lock sync.Mutex
+
+	CredentialLookup time.Duration
+	DNS              time.Duration
+	Connect          time.Duration
+	TLSHandshake     time.Duration
+	TimeToFirstByte  time.Duration
+	CrunchHead       time.Duration
+	BodyTransfer     time.Duration
+}
+
+// Phases returns the duration of every phase by name.
+func (t *Timings) Phases() map[string]time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return map[string]time.Duration{
+		"credential_lookup": t.CredentialLookup,
+		"dns":               t.DNS,
+		"connect":           t.Connect,
+		"tls_handshake":     t.TLSHandshake,
+		"ttfb":              t.TimeToFirstByte,
+		"crunch_head":       t.CrunchHead,
+		"body_transfer":     t.BodyTransfer,
+	}
+}
+
+// Fields returns the timings as structured log fields.
+func (t *Timings) Fields() []zap.Field {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return []zap.Field{
+		zap.Duration("credentialLookupDuration", t.CredentialLookup),
+		zap.Duration("dnsDuration", t.DNS),
+		zap.Duration("connectDuration", t.Connect),
+		zap.Duration("tlsHandshakeDuration", t.TLSHandshake),
+		zap.Duration("ttfbDuration", t.TimeToFirstByte),
+		zap.Duration("crunchHeadDuration", t.CrunchHead),
+		zap.Duration("bodyTransferDuration", t.BodyTransfer),
+	}
+}
+
+// Set sets a timing, it is safe for concurrent use.
+func (t *Timings) Set(f func(t *Timings)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	f(t)
+}
+
+// withUpstreamTrace returns a context recording the upstream connection phases of a request in t.
+// The time to first byte is measured from now.
+func (t *Timings) withUpstreamTrace(ctx context.Context) context.Context {
+	begin := time.Now()
+	var dnsStart, connectStart, tlsStart time.Time
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.Set(func(*Timings) { dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.Set(func(t *Timings) { t.DNS = time.Since(dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.Set(func(*Timings) { connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			t.Set(func(t *Timings) { t.Connect = time.Since(connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.Set(func(*Timings) { tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.Set(func(t *Timings) { t.TLSHandshake = time.Since(tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			t.Set(func(t *Timings) { t.TimeToFirstByte = time.Since(begin) })
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_TimingsUpstreamTrace(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("foo"))
+	}))
+	defer server.Close()
+
+	timings := &Timings{}
+	req, err := http.NewRequestWithContext(timings.withUpstreamTrace(context.Background()), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	phases := timings.Phases()
+	assert.Positive(t, phases["connect"])
+	assert.Positive(t, phases["tls_handshake"])
+	assert.Positive(t, phases["ttfb"])
+	assert.GreaterOrEqual(t, phases["ttfb"], phases["tls_handshake"])
+	// the server url is an ip, there is no dns lookup
+	assert.Zero(t, phases["dns"])
+	assert.Len(t, timings.Fields(), len(phases))
+}
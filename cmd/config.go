@@ -12,4 +12,13 @@ type Config struct {
 	App app.Config `yaml:"App"`
 }
 
-var DefaultConfig = Config{}
+// DEFAULT_ADMIN_ADDRESS is the address the admin api listens on, loopback only by default.
+const DEFAULT_ADMIN_ADDRESS = "127.0.0.1:7076"
+
+var DefaultConfig = Config{
+	Api: api.Config{
+		AdminAddress:               DEFAULT_ADMIN_ADDRESS,
+		ShutdownGracePeriodSeconds: 30,
+		DrainTimeoutSeconds:        25,
+	},
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/project-n-oss/sidekick/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPrintCmd)
+	configCmd.AddCommand(configEnvCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "inspects the sidekick configuration",
+	// overrides the root hooks, config commands only print the configuration
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "validates the configuration read from the config file and the environment",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRootConfig(cmd, config.WithStrict()); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+		if err := rootConfig.App.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+		return nil
+	},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "prints the effective configuration and where each value comes from",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sources := config.Sources{}
+		if err := loadRootConfig(cmd, config.WithSources(sources)); err != nil {
+			return err
+		}
+
+		// variables loaded from the .env file are in the environment, tell them apart
+		dotEnv, _ := godotenv.Read(dotEnvFile)
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tVALUE\tSOURCE")
+		for _, field := range config.Fields(cfgEnvPrefix, &rootConfig) {
+			source := sources.Get(field.Path)
+			if v, ok := dotEnv[field.EnvVar]; ok && source == "env:"+field.EnvVar && v == os.Getenv(field.EnvVar) {
+				source = dotEnvFile + ":" + field.EnvVar
+			}
+			fmt.Fprintf(w, "%s\t%v\t%s\n", field.Path, field.Value, source)
+		}
+		return w.Flush()
+	},
+}
+
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "lists every supported environment variable and flag",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VARIABLE\tFLAG\tTYPE\tFIELD")
+		for _, field := range config.Fields(cfgEnvPrefix, &DefaultConfig) {
+			// elements of slices of structs have no flag
+			flag := "-"
+			if !strings.Contains(field.Path, "<N>") {
+				flag = "--" + config.FlagName(field.Path)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", field.EnvVar, flag, field.Type, field.Path)
+		}
+		return w.Flush()
+	},
+}
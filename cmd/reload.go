@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/project-n-oss/sidekick/api"
+	"github.com/project-n-oss/sidekick/app"
+	"github.com/project-n-oss/sidekick/pkg/config"
+	"github.com/project-n-oss/sidekick/pkg/shutdown"
+	"go.uber.org/zap"
+)
+
+// configWatchInterval is how often the config file is checked for changes.
+const configWatchInterval = 5 * time.Second
+
+// configReloader reloads the configuration read with opts. Reloads are serialized.
+type configReloader struct {
+	lock sync.Mutex
+	opts []func(*config.UnmarshalConfigOptions)
+	// dir is the drop-in config directory, watched for added or removed files.
+	dir string
+}
+
+func newConfigReloader(opts []func(*config.UnmarshalConfigOptions), dir string) *configReloader {
+	return &configReloader{opts: opts, dir: dir}
+}
+
+// reload reads the configuration again, validates it and applies it to app and api.
+// Restart-only fields keep their current value, they are listed in the returned report.
+func (r *configReloader) reload(ctx context.Context, app *app.App, api *api.Api) (config.ReloadReport, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	next := DefaultConfig
+	if err := config.UnmarshalConfig(ctx, cfgEnvPrefix, &next, r.opts...); err != nil {
+		return config.ReloadReport{}, err
+	}
+	if err := next.App.Validate(); err != nil {
+		return config.ReloadReport{}, err
+	}
+
+	report := config.NewReloadReport(config.MergeReloadable(&rootConfig, &next))
+	if err := app.Reload(next.App); err != nil {
+		return config.ReloadReport{}, err
+	}
+	if err := api.Reload(next.Api); err != nil {
+		return config.ReloadReport{}, err
+	}
+	shutdown.WithGracePeriod(time.Duration(next.Api.ShutdownGracePeriodSeconds) * time.Second)
+	shutdown.WithPreStopDelay(time.Duration(next.Api.PreStopDelaySeconds) * time.Second)
+	rootConfig = next
+
+	return report, nil
+}
+
+// watch reloads the configuration on SIGHUP and when a config file changes, until ctx is done.
+// The files watched are those read at startup, plus the drop-in directory for added or removed files.
+func (r *configReloader) watch(ctx context.Context, app *app.App, api *api.Api) {
+	reload := func(trigger string) {
+		report, err := r.reload(ctx, app, api)
+		if err != nil {
+			rootLogger.Error("config reload failed, keeping the current config", zap.String("trigger", trigger), zap.Error(err))
+			return
+		}
+		rootLogger.Info("config reloaded", zap.String("trigger", trigger), zap.Strings("reloaded", report.Reloaded))
+		if len(report.RestartRequired) > 0 {
+			rootLogger.Warn("config fields changed but require a restart", zap.Strings("restartRequired", report.RestartRequired))
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				reload("SIGHUP")
+			}
+		}
+	}()
+
+	files, err := config.ConfigFiles(r.opts...)
+	if err != nil {
+		rootLogger.Error("could not list config files, they will not be watched", zap.Error(err))
+		return
+	}
+	if len(files) == 0 {
+		files = []string{config.DefaultFilePath}
+	}
+	if r.dir != "" {
+		files = append(files, r.dir)
+	}
+	for _, file := range files {
+		file := file
+		config.WatchFile(ctx, file, configWatchInterval, func() {
+			reload("file:" + file)
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"runtime/pprof"
 	"strings"
 	"syscall"
 
@@ -16,6 +15,7 @@ import (
 	"github.com/project-n-oss/sidekick/pkg/shutdown"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 //go:embed ascii.txt
@@ -29,17 +29,23 @@ func version() string {
 }
 
 func init() {
-	rootLogger, _ = logger.NewLogger(false)
+	rootLogger, rootLogLevel = logger.NewLogger(false)
 
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "make output more verbose")
-	rootCmd.PersistentFlags().StringP("config", "c", "", "read configuration from this file")
-	rootCmd.PersistentFlags().Bool("cpuprofile", false, "save cpu profile")
+	rootCmd.PersistentFlags().StringSliceP("config", "c", nil, "read configuration from these files, later files override earlier ones")
+	rootCmd.PersistentFlags().String("config-dir", "", "read configuration from every file of this directory, after the --config files or config.yml")
+	config.BindFlags(rootCmd.PersistentFlags(), cfgEnvPrefix, &DefaultConfig)
 }
 
 var (
-	rootLogger *zap.Logger
-	rootConfig = DefaultConfig
+	rootLogger   *zap.Logger
+	rootLogLevel zap.AtomicLevel
+	rootConfig   = DefaultConfig
+	// rootConfigOpts are the options rootConfig was loaded with, they are reused to reload it.
+	rootConfigOpts []func(*config.UnmarshalConfigOptions)
+	// rootConfigDir is the drop-in config directory, watched for changes.
+	rootConfigDir string
 )
 
 var rootCmd = &cobra.Command{
@@ -49,41 +55,12 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:  true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		verbose, _ := cmd.Flags().GetBool("verbose")
-		rootLogger, _ = logger.NewLogger(verbose)
+		rootLogger, rootLogLevel = logger.NewLogger(verbose)
 		shutdown.OnShutdown(func() {
 			rootLogger.Sync()
 		})
 
-		cpuprofile, _ := cmd.Flags().GetBool("cpuprofile")
-		if cpuprofile {
-			f, err := os.Create("cpu.prof")
-			if err != nil {
-				return fmt.Errorf("could not create CPU profile: %w", err)
-			}
-
-			if err := pprof.StartCPUProfile(f); err != nil {
-				return fmt.Errorf("could not start CPU profile: %w", err)
-			}
-			shutdown.OnShutdown(func() {
-				pprof.StopCPUProfile()
-				f.Close()
-			})
-		}
-
-		if _, err := os.Stat(".env"); err == nil {
-			err := godotenv.Load()
-			if err != nil {
-				return fmt.Errorf("could not load .env file. %v", err)
-			}
-		}
-
-		configFilePath, _ := cmd.Flags().GetString("config")
-		cfgOpts := []func(*config.UnmarshalConfigOptions){}
-		if configFilePath != "" {
-			cfgOpts = append(cfgOpts, config.WithFilePath(configFilePath))
-		}
-
-		if err := config.UnmarshalConfig(context.Background(), cfgEnvPrefix, &rootConfig, cfgOpts...); err != nil {
+		if err := loadRootConfig(cmd); err != nil {
 			return err
 		}
 
@@ -91,6 +68,9 @@ var rootCmd = &cobra.Command{
 		go func() {
 			waitForTermSignal()
 		}()
+		go func() {
+			waitForLogLevelSignal(rootLogLevel.Level())
+		}()
 
 		fmt.Println(bannerArt)
 		fmt.Printf("Version: %s\n", version())
@@ -104,6 +84,34 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// dotEnvFile is loaded into the environment, without overriding existing variables, before reading the config.
+const dotEnvFile = ".env"
+
+// loadRootConfig loads the .env file and reads rootConfig from the config file, the environment and the flags.
+func loadRootConfig(cmd *cobra.Command, opts ...func(*config.UnmarshalConfigOptions)) error {
+	if _, err := os.Stat(dotEnvFile); err == nil {
+		err := godotenv.Load(dotEnvFile)
+		if err != nil {
+			return fmt.Errorf("could not load .env file. %v", err)
+		}
+	}
+
+	// built from scratch, loading the config twice must not add the same files twice
+	loadOpts := []func(*config.UnmarshalConfigOptions){}
+	configFilePaths, _ := cmd.Flags().GetStringSlice("config")
+	for _, configFilePath := range configFilePaths {
+		loadOpts = append(loadOpts, config.WithFilePath(configFilePath))
+	}
+	configDir, _ := cmd.Flags().GetString("config-dir")
+	if configDir != "" {
+		loadOpts = append(loadOpts, config.WithDropInDir(configDir))
+	}
+	loadOpts = append(loadOpts, config.WithFlags(cmd.Flags()))
+	rootConfigOpts, rootConfigDir = loadOpts, configDir
+
+	return config.UnmarshalConfig(context.Background(), cfgEnvPrefix, &rootConfig, append(loadOpts[:len(loadOpts):len(loadOpts)], opts...)...)
+}
+
 func waitForTermSignal() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT)
@@ -115,6 +123,21 @@ func waitForTermSignal() {
 	shutdown.Shutdown()
 }
 
+// waitForLogLevelSignal switches the log level to debug on SIGUSR1 and back to defaultLevel on SIGUSR2.
+func waitForLogLevelSignal(defaultLevel zapcore.Level) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	for sig := range sigs {
+		level := defaultLevel
+		if sig == syscall.SIGUSR1 {
+			level = zapcore.DebugLevel
+		}
+		rootLogLevel.SetLevel(level)
+		rootLogger.Info("received signal, changed log level", zap.String("signal", sig.String()), zap.Stringer("level", level))
+	}
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		rootLogger.Fatal(err.Error())
@@ -3,13 +3,16 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/project-n-oss/sidekick/api"
 	"github.com/project-n-oss/sidekick/app"
 	"github.com/project-n-oss/sidekick/pkg/shutdown"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 // DEFAULT_PORT
@@ -28,7 +31,7 @@ var serveCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		shutdown.OnShutdown(cancel)
 
-		app, err := app.New(ctx, rootLogger, rootConfig.App)
+		app, err := app.New(ctx, rootLogger, rootLogLevel, rootConfig.App)
 		if err != nil {
 			return fmt.Errorf("could not create app: %w", err)
 		}
@@ -38,20 +41,46 @@ var serveCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		shutdown.OnShutdown(func() {
+			api.Close(context.Background())
+		})
 
+		// requestsCtx is the base context of every request, it is cancelled once the drain deadline is over
+		requestsCtx, cancelRequests := context.WithCancel(ctx)
 		handler := api.CreateHandler()
 		server := &http.Server{
 			Addr:    ":" + strconv.Itoa(port),
 			Handler: handler,
+			BaseContext: func(net.Listener) context.Context {
+				return requestsCtx
+			},
 		}
 
-		go func() {
-			<-ctx.Done()
-			if err := server.Shutdown(context.Background()); err != nil {
-				rootLogger.Error("error shutting down server")
-				rootLogger.Error(err.Error())
+		shutdown.WithGracePeriod(time.Duration(rootConfig.Api.ShutdownGracePeriodSeconds) * time.Second)
+		shutdown.WithPreStopDelay(time.Duration(rootConfig.Api.PreStopDelaySeconds) * time.Second)
+		shutdown.OnPhase(shutdown.PhaseDrain, func(ctx context.Context) {
+			drainCtx, cancel := context.WithTimeout(ctx, time.Duration(api.Config().DrainTimeoutSeconds)*time.Second)
+			defer cancel()
+
+			rootLogger.Info("draining in-flight requests", zap.Int64("inFlight", api.InFlight()))
+			if err := server.Shutdown(drainCtx); err != nil {
+				cutOff := api.InFlight()
+				cancelRequests()
+				server.Close()
+				rootLogger.Warn("drain deadline exceeded, cancelled in-flight requests", zap.Int64("cutOff", cutOff), zap.Error(err))
+				return
 			}
-		}()
+			rootLogger.Info("drained in-flight requests", zap.Int64("cutOff", 0))
+		})
+
+		if adminAddress := rootConfig.Api.AdminAddress; adminAddress != "" {
+			serveInBackground("admin api", adminAddress, api.CreateAdminHandler())
+		}
+		if healthAddress := rootConfig.Api.HealthAddress; healthAddress != "" {
+			serveInBackground("health checks", healthAddress, api.CreateHealthHandler())
+		}
+
+		newConfigReloader(rootConfigOpts, rootConfigDir).watch(ctx, app, api)
 
 		rootLogger.Sugar().Infof("listening at http://localhost:%v", port)
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
@@ -61,3 +90,21 @@ var serveCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// serveInBackground serves handler at address until shutdown.
+func serveInBackground(name string, address string, handler http.Handler) {
+	server := &http.Server{
+		Addr:    address,
+		Handler: handler,
+	}
+	shutdown.OnShutdown(func() {
+		server.Close()
+	})
+
+	go func() {
+		rootLogger.Sugar().Infof("%s listening at http://%v", name, address)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			rootLogger.Error(name+" stopped", zap.Error(err))
+		}
+	}()
+}
@@ -2,20 +2,51 @@ package config
 
 import (
 	"context"
+
+	"github.com/spf13/pflag"
 )
 
 type UnmarshalConfigOptions struct {
-	filePath string
+	filePaths       []string
+	dropInDir       string
+	strict          bool
+	sources         Sources
+	secretProviders map[string]SecretProvider
+	flags           *pflag.FlagSet
+}
+
+// WithStrict fails decoding config files with unknown or duplicate fields.
+func WithStrict() func(*UnmarshalConfigOptions) {
+	return func(options *UnmarshalConfigOptions) {
+		options.strict = true
+	}
 }
 
-// UnmarshalConfig populates config with values from environment variables and a yaml file.
+// UnmarshalConfig populates config with values from a yaml file, environment variables and flags,
+// then resolves the secrets it references.
 func UnmarshalConfig(ctx context.Context, prefix string, config any, opts ...func(*UnmarshalConfigOptions)) error {
 	err := UnmarshalConfigFromFile(config, opts...)
 	if err != nil {
 		return err
 	}
 
-	err = UnmarshalConfigFromEnv(ctx, prefix, config)
+	err = UnmarshalConfigFromEnv(ctx, prefix, config, opts...)
+	if err != nil {
+		return err
+	}
+
+	options := UnmarshalConfigOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.flags != nil {
+		err = UnmarshalConfigFromFlags(prefix, config, options.flags, opts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ResolveSecrets(ctx, config, opts...)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -62,3 +64,154 @@ func TestUnmarshalConfig(t *testing.T) {
 		})
 	}
 }
+
+type Rule struct {
+	Name   string   `yaml:"Name"`
+	Weight float64  `yaml:"Weight"`
+	Tags   []string `yaml:"Tags"`
+}
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type TypesConfig struct {
+	Timeout  time.Duration     `yaml:"Timeout"`
+	Ratio    float64           `yaml:"Ratio"`
+	Big      int64             `yaml:"Big"`
+	Count    uint              `yaml:"Count"`
+	MaxSize  ByteSize          `yaml:"MaxSize"`
+	Labels   map[string]string `yaml:"Labels"`
+	Level    level             `yaml:"Level"`
+	Pointer  *int              `yaml:"Pointer"`
+	Rules    []Rule            `yaml:"Rules"`
+	Retries  []int             `yaml:"Retries"`
+	Disabled bool              `yaml:"Disabled"`
+}
+
+func TestUnmarshalConfig_Types(t *testing.T) {
+	pointer := 3
+	for name, tc := range map[string]struct {
+		Environment map[string]string
+		In          TypesConfig
+		Expected    *TypesConfig
+		Err         string
+	}{
+		"Scalars": {
+			Environment: map[string]string{
+				"TEST_TIMEOUT":  "1m30s",
+				"TEST_RATIO":    "0.25",
+				"TEST_BIG":      "9007199254740993",
+				"TEST_COUNT":    "7",
+				"TEST_MAXSIZE":  "64MiB",
+				"TEST_LABELS":   "team=storage, env=prod",
+				"TEST_LEVEL":    "high",
+				"TEST_POINTER":  "3",
+				"TEST_RETRIES":  "1, 2,3",
+				"TEST_DISABLED": "true",
+			},
+			Expected: &TypesConfig{
+				Timeout:  90 * time.Second,
+				Ratio:    0.25,
+				Big:      9007199254740993,
+				Count:    7,
+				MaxSize:  64 << 20,
+				Labels:   map[string]string{"team": "storage", "env": "prod"},
+				Level:    2,
+				Pointer:  &pointer,
+				Retries:  []int{1, 2, 3},
+				Disabled: true,
+			},
+		},
+		"SliceOfStructs": {
+			Environment: map[string]string{
+				"TEST_RULES_0_WEIGHT": "0.5",
+				"TEST_RULES_1_NAME":   "second",
+				"TEST_RULES_1_TAGS":   "a,b",
+				"TEST_RULES_3_NAME":   "ignored",
+			},
+			In: TypesConfig{
+				Rules: []Rule{{Name: "first", Weight: 1}},
+			},
+			Expected: &TypesConfig{
+				Rules: []Rule{
+					{Name: "first", Weight: 0.5},
+					{Name: "second", Tags: []string{"a", "b"}},
+				},
+			},
+		},
+		"InvalidDuration": {
+			Environment: map[string]string{"TEST_TIMEOUT": "30"},
+			Err:         "TEST_TIMEOUT",
+		},
+		"InvalidUint": {
+			Environment: map[string]string{"TEST_COUNT": "-1"},
+			Err:         "TEST_COUNT",
+		},
+		"InvalidByteSize": {
+			Environment: map[string]string{"TEST_MAXSIZE": "12 parsecs"},
+			Err:         "TEST_MAXSIZE",
+		},
+		"InvalidMap": {
+			Environment: map[string]string{"TEST_LABELS": "team"},
+			Err:         "TEST_LABELS",
+		},
+		"InvalidTextUnmarshaler": {
+			Environment: map[string]string{"TEST_LEVEL": "medium"},
+			Err:         "TEST_LEVEL",
+		},
+		"InvalidSliceOfStructsElement": {
+			Environment: map[string]string{"TEST_RULES_0_WEIGHT": "heavy"},
+			Err:         "TEST_RULES_0_WEIGHT",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := tc.In
+			_, err := unmarshalConfig("TEST", reflect.ValueOf(&config), func(key string) (*string, error) {
+				if v, ok := tc.Environment[key]; ok {
+					return &v, nil
+				}
+				return nil, nil
+			})
+			if tc.Err != "" {
+				assert.ErrorContains(t, err, tc.Err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, &config)
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for in, expected := range map[string]ByteSize{
+		"1024":   1024,
+		"10B":    10,
+		"1KB":    1000,
+		"1KiB":   1024,
+		"1.5GB":  1500000000,
+		"512Mi":  512 << 20,
+		"2 GiB":  2 << 30,
+		"1T":     1 << 40,
+		"100 kb": 100000,
+	} {
+		size, err := ParseByteSize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, expected, size, in)
+	}
+
+	_, err := ParseByteSize("1XB")
+	assert.Error(t, err)
+	assert.Equal(t, "64MiB", ByteSize(64<<20).String())
+	assert.Equal(t, "1500B", ByteSize(1500).String())
+}
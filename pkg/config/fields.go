@@ -0,0 +1,125 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Field is a leaf field of a config struct.
+type Field struct {
+	// Path is the path of the field, using its yaml name, e.g. App.NoCrunchErr.
+	Path string
+	// EnvVar is the name of the environment variable the field is read from.
+	EnvVar string
+	// Type is the go type of the field.
+	Type string
+	// Value is the current value of the field, nil for nil pointers.
+	Value any
+}
+
+// Fields lists the leaf fields of config, a pointer to a struct, with the environment variable each field
+// is read from. Environment variable names are derived the same way UnmarshalConfigFromEnv does.
+func Fields(prefix string, config any) []Field {
+	return fields(prefix, "", reflect.ValueOf(config).Type().Elem(), reflect.ValueOf(config).Elem())
+}
+
+// fields walks t, v may be invalid when a parent pointer is nil.
+func fields(envVar, path string, t reflect.Type, v reflect.Value) []Field {
+	if t.Kind() == reflect.Ptr && !isLeaf(t.Elem()) {
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Value{}
+		}
+		t = t.Elem()
+	}
+
+	if isLeaf(t) || t.Kind() == reflect.Ptr {
+		var value any
+		if v.IsValid() && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+			value = reflect.Indirect(v).Interface()
+		}
+		return []Field{{
+			Path:   path,
+			EnvVar: envVar,
+			Type:   t.String(),
+			Value:  value,
+		}}
+	}
+
+	// slices of structs list the fields of each element, or a template element when empty.
+	if t.Kind() == reflect.Slice {
+		if !v.IsValid() || v.Len() == 0 {
+			return fields(envVar+"_<N>", path+".<N>", t.Elem(), reflect.Value{})
+		}
+		ret := []Field{}
+		for i := 0; i < v.Len(); i++ {
+			ret = append(ret, fields(envVar+"_"+strconv.Itoa(i), path+"."+strconv.Itoa(i), t.Elem(), v.Index(i))...)
+		}
+		return ret
+	}
+
+	ret := []Field{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := fieldName(field)
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		var fieldValue reflect.Value
+		if v.IsValid() {
+			fieldValue = v.Field(i)
+		}
+		ret = append(ret, fields(envVar+"_"+strings.ToUpper(field.Name), fieldPath, field.Type, fieldValue)...)
+	}
+	return ret
+}
+
+// Sources maps the path of config fields to where their value was read from, see WithSources.
+// Fields that are not in Sources have their default value.
+type Sources map[string]string
+
+const SourceDefault = "default"
+
+// Get returns the source of the field at path. Fields nested in a value read as a whole, like the elements of
+// a slice of structs set in a config file, have the source of that value.
+func (s Sources) Get(path string) string {
+	for {
+		if source, ok := s[path]; ok {
+			return source
+		}
+		i := strings.LastIndex(path, ".")
+		if i == -1 {
+			return SourceDefault
+		}
+		path = path[:i]
+	}
+}
+
+// WithSources records in sources where the value of each config field was read from.
+func WithSources(sources Sources) func(*UnmarshalConfigOptions) {
+	return func(options *UnmarshalConfigOptions) {
+		options.sources = sources
+	}
+}
+
+// recordFileSources marks every leaf key of a decoded config file as read from source.
+func recordFileSources(sources Sources, path string, v any, source string) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		if path != "" {
+			sources[path] = source
+		}
+		return
+	}
+	for keyPath, value := range m {
+		if path != "" {
+			keyPath = path + "." + keyPath
+		}
+		recordFileSources(sources, keyPath, value, source)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type FieldsConfig struct {
+	Foo    string     `yaml:"Foo"`
+	SubFoo SubFoo     `yaml:"SubFoo"`
+	PtrFoo *FieldsSub `yaml:"PtrFoo"`
+	Bars   []string   `yaml:"Bars"`
+}
+
+type FieldsSub struct {
+	Bar *int `yaml:"Bar"`
+}
+
+func TestFields(t *testing.T) {
+	config := FieldsConfig{Foo: "foo", SubFoo: SubFoo{SubBar: 1}}
+	assert.Equal(t, []Field{
+		{Path: "Foo", EnvVar: "TEST_FOO", Type: "string", Value: "foo"},
+		{Path: "SubFoo.SubBar", EnvVar: "TEST_SUBFOO_SUBBAR", Type: "int", Value: 1},
+		{Path: "SubFoo.PointerSubBar", EnvVar: "TEST_SUBFOO_POINTERSUBBAR", Type: "int", Value: 0},
+		{Path: "PtrFoo.Bar", EnvVar: "TEST_PTRFOO_BAR", Type: "*int", Value: nil},
+		{Path: "Bars", EnvVar: "TEST_BARS", Type: "[]string", Value: []string(nil)},
+	}, Fields("TEST", &config))
+}
+
+func TestFields_SliceOfStructs(t *testing.T) {
+	config := TypesConfig{MaxSize: 1 << 20}
+	fields := Fields("TEST", &config)
+	assert.Contains(t, fields, Field{Path: "MaxSize", EnvVar: "TEST_MAXSIZE", Type: "config.ByteSize", Value: ByteSize(1 << 20)})
+	assert.Contains(t, fields, Field{Path: "Rules.<N>.Name", EnvVar: "TEST_RULES_<N>_NAME", Type: "string"})
+
+	config.Rules = []Rule{{Name: "first"}, {Name: "second"}}
+	fields = Fields("TEST", &config)
+	assert.Contains(t, fields, Field{Path: "Rules.0.Name", EnvVar: "TEST_RULES_0_NAME", Type: "string", Value: "first"})
+	assert.Contains(t, fields, Field{Path: "Rules.1.Name", EnvVar: "TEST_RULES_1_NAME", Type: "string", Value: "second"})
+}
+
+func TestUnmarshalConfig_Sources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("Foo: foo\nSubFoo:\n  SubBar: 1\n"), 0o644))
+	t.Setenv("TEST_SUBFOO_SUBBAR", "2")
+
+	config := FieldsConfig{}
+	sources := Sources{}
+	require.NoError(t, UnmarshalConfig(context.Background(), "TEST", &config, WithFilePath(path), WithSources(sources)))
+	assert.Equal(t, 2, config.SubFoo.SubBar)
+	assert.Equal(t, "file:"+path, sources.Get("Foo"))
+	assert.Equal(t, "env:TEST_SUBFOO_SUBBAR", sources.Get("SubFoo.SubBar"))
+	assert.Equal(t, SourceDefault, sources.Get("Bars"))
+
+	require.NoError(t, os.WriteFile(path, []byte("Fooo: foo\n"), 0o644))
+	assert.Error(t, UnmarshalConfig(context.Background(), "TEST", &config, WithFilePath(path), WithStrict()))
+}
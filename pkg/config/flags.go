@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// WithFlags applies the config flags set in flags, see BindFlags. Flags take precedence over environment
+// variables, which take precedence over config files.
+func WithFlags(flags *pflag.FlagSet) func(*UnmarshalConfigOptions) {
+	return func(options *UnmarshalConfigOptions) {
+		options.flags = flags
+	}
+}
+
+// FlagName returns the name of the flag of the config field at path: App.NoCrunchErr is app.no-crunch-err.
+func FlagName(path string) string {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		// slice index templates are kept as is
+		if part != "<N>" {
+			parts[i] = kebabCase(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func kebabCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			// a new word starts after a lower case letter, or at the last capital of an acronym: CPUProfile
+			if !unicode.IsUpper(prev) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteRune('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// flagValue holds the raw value of a config flag, it is parsed like the matching environment variable.
+// Only the values of flags set on the command line are applied.
+type flagValue struct {
+	value string
+	typ   string
+	check func(string) error
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) Set(s string) error {
+	if err := f.check(s); err != nil {
+		return err
+	}
+	f.value = s
+	return nil
+}
+
+func (f *flagValue) Type() string {
+	return f.typ
+}
+
+// BindFlags adds a flag to flags for every leaf field of config, a pointer to a struct, e.g. --app.cloud-platform
+// for App.CloudPlatform. Flags accept the same values as environment variables.
+func BindFlags(flags *pflag.FlagSet, prefix string, config any) {
+	configType := reflect.TypeOf(config).Elem()
+	for _, field := range Fields(prefix, config) {
+		// elements of slices of structs can only be set from files and environment variables
+		if strings.Contains(field.Path, "<N>") {
+			continue
+		}
+
+		envVar := field.EnvVar
+		value := &flagValue{
+			typ: field.Type,
+			check: func(s string) error {
+				_, err := unmarshalConfig(prefix, reflect.New(configType), func(key string) (*string, error) {
+					if key == envVar {
+						return &s, nil
+					}
+					return nil, nil
+				})
+				return err
+			},
+		}
+		// the current value is shown as the default in the usage
+		if field.Value != nil && !reflect.ValueOf(field.Value).IsZero() {
+			value.value = formatValue(reflect.ValueOf(field.Value))
+		}
+		flag := flags.VarPF(value, FlagName(field.Path), "", fmt.Sprintf("sets %s, like %s", field.Path, field.EnvVar))
+		if strings.TrimPrefix(field.Type, "*") == "bool" {
+			flag.NoOptDefVal = "true"
+		}
+	}
+}
+
+// UnmarshalConfigFromFlags populates config with the values of the flags bound with BindFlags and set in flags.
+func UnmarshalConfigFromFlags(prefix string, config any, flags *pflag.FlagSet, opts ...func(*UnmarshalConfigOptions)) error {
+	options := UnmarshalConfigOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	set := map[string]string{}
+	for _, field := range Fields(prefix, config) {
+		name := FlagName(field.Path)
+		if flag := flags.Lookup(name); flag != nil && flag.Changed {
+			set[field.EnvVar] = name
+		}
+	}
+
+	_, err := unmarshalConfig(prefix, reflect.ValueOf(config), func(key string) (*string, error) {
+		name, ok := set[key]
+		if !ok {
+			return nil, nil
+		}
+		v := flags.Lookup(name).Value.String()
+		return &v, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if options.sources != nil {
+		for _, field := range Fields(prefix, config) {
+			if name, ok := set[field.EnvVar]; ok {
+				options.sources[field.Path] = "flag:--" + name
+			}
+		}
+	}
+	return nil
+}
+
+// formatValue formats v the way it is written in environment variables.
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case v.Kind() == reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	case v.Kind() == reflect.Map:
+		parts := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			parts = append(parts, formatValue(key)+"="+formatValue(v.MapIndex(key)))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagName(t *testing.T) {
+	for path, expected := range map[string]string{
+		"App.CloudPlatform":        "app.cloud-platform",
+		"App.NoCrunchErr":          "app.no-crunch-err",
+		"Api.CPUProfile":           "api.cpu-profile",
+		"Api.ShutdownGracePeriod2": "api.shutdown-grace-period2",
+		"Foo":                      "foo",
+		"App.Routes.<N>.Bucket":    "app.routes.<N>.bucket",
+	} {
+		assert.Equal(t, expected, FlagName(path), path)
+	}
+}
+
+func TestUnmarshalConfig_Flags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("Foo: file\nSubFoo:\n  SubBar: 1\n  PointerSubBar: 1\n"), 0o644))
+	t.Setenv("TEST_SUBFOO_SUBBAR", "2")
+	t.Setenv("TEST_SUBFOO_POINTERSUBBAR", "2")
+
+	defaults := FieldsConfig{Bars: []string{"default"}}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	BindFlags(flags, "TEST", &defaults)
+	assert.Equal(t, "default", flags.Lookup("bars").DefValue)
+	assert.Error(t, flags.Parse([]string{"--sub-foo.sub-bar=three"}))
+	require.NoError(t, flags.Parse([]string{"--sub-foo.sub-bar=3", "--ptr-foo.bar", "4"}))
+
+	config := defaults
+	sources := Sources{}
+	require.NoError(t, UnmarshalConfig(context.Background(), "TEST", &config, WithFilePath(path), WithFlags(flags), WithSources(sources)))
+
+	// flag > env > file > default
+	assert.Equal(t, 3, config.SubFoo.SubBar)
+	assert.Equal(t, 2, config.SubFoo.PointerSubBar)
+	assert.Equal(t, "file", config.Foo)
+	assert.Equal(t, []string{"default"}, config.Bars)
+	require.NotNil(t, config.PtrFoo)
+	assert.Equal(t, 4, *config.PtrFoo.Bar)
+	assert.Equal(t, "flag:--sub-foo.sub-bar", sources.Get("SubFoo.SubBar"))
+	assert.Equal(t, "env:TEST_SUBFOO_POINTERSUBBAR", sources.Get("SubFoo.PointerSubBar"))
+}
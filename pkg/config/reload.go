@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// reloadTag marks config fields that cannot be changed without a restart: `reload:"restart"`.
+// Nested fields of a restart-only struct are restart-only as well.
+const reloadTag = "reload"
+
+// FieldChange is a config field whose value changed during a reload.
+type FieldChange struct {
+	// Path is the path of the field, using its yaml name, e.g. App.NoCrunchErr.
+	Path string
+	// Reloadable is false if the field requires a restart to change.
+	Reloadable bool
+}
+
+// ReloadReport lists the fields changed by a reload.
+type ReloadReport struct {
+	Reloaded        []string
+	RestartRequired []string
+}
+
+// NewReloadReport splits changes between reloaded and restart-only fields.
+func NewReloadReport(changes []FieldChange) ReloadReport {
+	ret := ReloadReport{
+		Reloaded:        []string{},
+		RestartRequired: []string{},
+	}
+	for _, change := range changes {
+		if change.Reloadable {
+			ret.Reloaded = append(ret.Reloaded, change.Path)
+		} else {
+			ret.RestartRequired = append(ret.RestartRequired, change.Path)
+		}
+	}
+	return ret
+}
+
+// MergeReloadable compares the current and next configs, both pointers to the same struct type, and returns
+// the changed fields. The restart-only fields of next are reset to their current value, so that next can be
+// applied without a restart.
+func MergeReloadable(current, next any) []FieldChange {
+	return mergeReloadable("", reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem(), true)
+}
+
+func mergeReloadable(path string, current, next reflect.Value, reloadable bool) []FieldChange {
+	if current.Kind() == reflect.Struct && !isLeaf(current.Type()) {
+		ret := []FieldChange{}
+		t := current.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := fieldName(field)
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			fieldReloadable := reloadable && field.Tag.Get(reloadTag) != "restart"
+			ret = append(ret, mergeReloadable(fieldPath, current.Field(i), next.Field(i), fieldReloadable)...)
+		}
+		return ret
+	}
+
+	if reflect.DeepEqual(current.Interface(), next.Interface()) {
+		return nil
+	}
+	if !reloadable {
+		next.Set(current)
+	}
+	return []FieldChange{{Path: path, Reloadable: reloadable}}
+}
+
+// fieldName returns the yaml name of a struct field.
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// WatchFile calls onChange every time the modification time or the size of the file at path changes.
+// The file is polled every interval until ctx is done.
+func WatchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	modTime, size := stat()
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				newModTime, newSize := stat()
+				if !newModTime.Equal(modTime) || newSize != size {
+					modTime, size = newModTime, newSize
+					onChange()
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ReloadConfig struct {
+	Address string       `yaml:"Address" reload:"restart"`
+	Verbose bool         `yaml:"Verbose"`
+	Sub     ReloadSubFoo `yaml:"Sub,omitempty"`
+	Frozen  ReloadSubFoo `yaml:"Frozen" reload:"restart"`
+}
+
+type ReloadSubFoo struct {
+	Names []string `yaml:"Names"`
+}
+
+func TestMergeReloadable(t *testing.T) {
+	current := ReloadConfig{
+		Address: ":7075",
+		Sub:     ReloadSubFoo{Names: []string{"foo"}},
+		Frozen:  ReloadSubFoo{Names: []string{"foo"}},
+	}
+	next := ReloadConfig{
+		Address: ":8080",
+		Verbose: true,
+		Sub:     ReloadSubFoo{Names: []string{"bar"}},
+		Frozen:  ReloadSubFoo{Names: []string{"bar"}},
+	}
+
+	changes := MergeReloadable(&current, &next)
+	assert.Equal(t, []FieldChange{
+		{Path: "Address", Reloadable: false},
+		{Path: "Verbose", Reloadable: true},
+		{Path: "Sub.Names", Reloadable: true},
+		{Path: "Frozen.Names", Reloadable: false},
+	}, changes)
+	assert.Equal(t, ReloadConfig{
+		Address: ":7075",
+		Verbose: true,
+		Sub:     ReloadSubFoo{Names: []string{"bar"}},
+		Frozen:  ReloadSubFoo{Names: []string{"foo"}},
+	}, next)
+
+	assert.Equal(t, ReloadReport{
+		Reloaded:        []string{"Verbose", "Sub.Names"},
+		RestartRequired: []string{"Address", "Frozen.Names"},
+	}, NewReloadReport(changes))
+}
+
+func TestWatchFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("Foo: foo"), 0o644))
+
+	changed := make(chan struct{}, 1)
+	WatchFile(ctx, path, 10*time.Millisecond, func() {
+		changed <- struct{}{}
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte("Foo: foobar"), 0o644))
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("file change not detected")
+	}
+}
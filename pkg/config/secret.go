@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+// SecretValue is a config value that must not be printed. It is either written as is, or as a reference to
+// a secret resolved by a SecretProvider when the config is loaded:
+//
+//	file:///run/secrets/key  reads the file, without its trailing newline
+//	env:NAME                 reads the NAME environment variable
+//	<scheme>:<ref>           uses the provider registered for scheme with WithSecretProvider
+//
+// Values that do not start with a known scheme are used as is. Dumps only show references, never values.
+type SecretValue struct {
+	ref   string
+	value string
+}
+
+// NewSecretValue returns a secret for ref, references are resolved by ResolveSecrets.
+func NewSecretValue(ref string) SecretValue {
+	return SecretValue{ref: ref, value: ref}
+}
+
+// Value returns the secret, the resolved value for references.
+func (s SecretValue) Value() string {
+	return s.value
+}
+
+// Ref returns the secret as written in the config.
+func (s SecretValue) Ref() string {
+	return s.ref
+}
+
+// IsZero returns true if no secret was set.
+func (s SecretValue) IsZero() bool {
+	return s.ref == ""
+}
+
+func (s SecretValue) String() string {
+	if s.ref == "" {
+		return ""
+	}
+	if _, _, ok := splitSecretRef(s.ref, defaultSecretProviders); ok {
+		return s.ref
+	}
+	return redacted
+}
+
+func (s SecretValue) GoString() string {
+	return s.String()
+}
+
+func (s SecretValue) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *SecretValue) UnmarshalText(text []byte) error {
+	*s = NewSecretValue(string(text))
+	return nil
+}
+
+func (s SecretValue) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+func (s *SecretValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var ref string
+	if err := unmarshal(&ref); err != nil {
+		return err
+	}
+	*s = NewSecretValue(ref)
+	return nil
+}
+
+// SecretProvider resolves the secrets referenced with its scheme.
+type SecretProvider interface {
+	// Resolve returns the secret for ref, the reference without its scheme, e.g. "/run/secrets/key" for
+	// "file:///run/secrets/key".
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SecretProviderFunc adapts a function to a SecretProvider.
+type SecretProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretProviderFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var defaultSecretProviders = map[string]SecretProvider{
+	"file": SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		content, err := os.ReadFile(ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}),
+	"env": SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		v, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return v, nil
+	}),
+}
+
+// WithSecretProvider resolves the secrets referenced as <scheme>:<ref> with provider.
+func WithSecretProvider(scheme string, provider SecretProvider) func(*UnmarshalConfigOptions) {
+	return func(options *UnmarshalConfigOptions) {
+		if options.secretProviders == nil {
+			options.secretProviders = map[string]SecretProvider{}
+		}
+		options.secretProviders[scheme] = provider
+	}
+}
+
+// ResolveSecrets resolves every SecretValue reference of config, a pointer to a struct.
+func ResolveSecrets(ctx context.Context, config any, opts ...func(*UnmarshalConfigOptions)) error {
+	options := UnmarshalConfigOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	providers := map[string]SecretProvider{}
+	for scheme, provider := range defaultSecretProviders {
+		providers[scheme] = provider
+	}
+	for scheme, provider := range options.secretProviders {
+		providers[scheme] = provider
+	}
+
+	return resolveSecrets(ctx, "", reflect.ValueOf(config), providers)
+}
+
+var secretValueType = reflect.TypeOf(SecretValue{})
+
+func resolveSecrets(ctx context.Context, path string, v reflect.Value, providers map[string]SecretProvider) error {
+	switch {
+	case v.Type() == secretValueType:
+		secret := v.Addr().Interface().(*SecretValue)
+		scheme, ref, ok := splitSecretRef(secret.ref, providers)
+		if !ok {
+			secret.value = secret.ref
+			return nil
+		}
+		value, err := providers[scheme].Resolve(ctx, ref)
+		if err != nil {
+			// the error of a provider must not contain the secret
+			return fmt.Errorf("failed to resolve secret %s from %s: %w", path, secret.ref, err)
+		}
+		secret.value = value
+	case v.Kind() == reflect.Ptr:
+		if !v.IsNil() {
+			return resolveSecrets(ctx, path, v.Elem(), providers)
+		}
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			fieldPath := fieldName(t.Field(i))
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			if err := resolveSecrets(ctx, fieldPath, v.Field(i), providers); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveSecrets(ctx, fmt.Sprintf("%s.%d", path, i), v.Index(i), providers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitSecretRef returns the scheme and the reference of a secret, ok is false for plain values.
+func splitSecretRef(s string, providers map[string]SecretProvider) (scheme, ref string, ok bool) {
+	scheme, ref, ok = strings.Cut(s, ":")
+	if !ok {
+		return "", "", false
+	}
+	if _, ok := providers[scheme]; !ok {
+		return "", "", false
+	}
+	return scheme, strings.TrimPrefix(ref, "//"), true
+}
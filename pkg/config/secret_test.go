@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+type SecretsConfig struct {
+	File   SecretValue   `yaml:"File"`
+	Env    SecretValue   `yaml:"Env"`
+	Plain  SecretValue   `yaml:"Plain"`
+	Vault  SecretValue   `yaml:"Vault"`
+	Nested *SecretsSub   `yaml:"Nested"`
+	List   []SecretValue `yaml:"List"`
+}
+
+type SecretsSub struct {
+	Key SecretValue `yaml:"Key"`
+}
+
+func TestUnmarshalConfig_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secretPath, []byte("from-file\n"), 0o600))
+	configPath := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(configPath, []byte(fmt.Sprintf(`
+File: file://%s
+Plain: hunter2
+Vault: vault:kv/sidekick
+Nested:
+  Key: env:TEST_SECRET_KEY
+List:
+  - env:TEST_SECRET_KEY
+`, secretPath)), 0o644))
+	t.Setenv("TEST_SECRET_KEY", "from-env")
+	t.Setenv("TEST_ENV", "env:TEST_SECRET_KEY")
+
+	vault := SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "from-vault:" + ref, nil
+	})
+	config := SecretsConfig{}
+	require.NoError(t, UnmarshalConfig(context.Background(), "TEST", &config, WithFilePath(configPath), WithSecretProvider("vault", vault)))
+
+	assert.Equal(t, "from-file", config.File.Value())
+	assert.Equal(t, "from-env", config.Env.Value())
+	assert.Equal(t, "hunter2", config.Plain.Value())
+	assert.Equal(t, "from-vault:kv/sidekick", config.Vault.Value())
+	assert.Equal(t, "from-env", config.Nested.Key.Value())
+	assert.Equal(t, "from-env", config.List[0].Value())
+
+	// dumps show references but never values
+	for _, dump := range []string{
+		fmt.Sprintf("%v %+v %#v", config, config, config),
+		mustMarshal(t, json.Marshal, config),
+		mustMarshal(t, yaml.Marshal, config),
+	} {
+		assert.NotContains(t, dump, "from-")
+		assert.NotContains(t, dump, "hunter2")
+		assert.Contains(t, dump, "env:TEST_SECRET_KEY")
+	}
+	for _, field := range Fields("TEST", &config) {
+		assert.NotContains(t, fmt.Sprint(field.Value), "from-")
+	}
+}
+
+func TestResolveSecrets_Errors(t *testing.T) {
+	config := SecretsConfig{
+		Nested: &SecretsSub{Key: NewSecretValue("env:TEST_SECRET_UNSET")},
+	}
+	err := ResolveSecrets(context.Background(), &config)
+	assert.ErrorContains(t, err, "Nested.Key")
+
+	config = SecretsConfig{File: NewSecretValue("file:///does/not/exist")}
+	assert.ErrorIs(t, ResolveSecrets(context.Background(), &config), os.ErrNotExist)
+
+	failing := SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "", errors.New("unavailable")
+	})
+	config = SecretsConfig{Vault: NewSecretValue("vault:kv/sidekick")}
+	assert.ErrorContains(t, ResolveSecrets(context.Background(), &config, WithSecretProvider("vault", failing)), "unavailable")
+}
+
+func mustMarshal(t *testing.T, marshal func(any) ([]byte, error), v any) string {
+	buf, err := marshal(v)
+	require.NoError(t, err)
+	return string(buf)
+}
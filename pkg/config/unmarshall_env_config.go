@@ -2,18 +2,16 @@ package config
 
 import (
 	"context"
+	"encoding"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
-type SecretValue struct {
-	Value string `json:"value"`
-}
-
 // UnmarshalConfigFromEnv populates config with values from environment variables. The names of the
 // environment variables read are formed by joining the config struct's field names with underscores
 // and adding a user defined prefix. for example consider this config struct:
@@ -32,67 +30,51 @@ type SecretValue struct {
 // TEST_FOO
 // TEST_SUBFOO_SUBBAR
 // TEST_SUBFOO_POINTERBAR
-func UnmarshalConfigFromEnv(ctx context.Context, prefix string, config any) error {
+//
+// Besides strings, bools and numbers, fields can be time.Duration ("30s"), ByteSize ("512MiB"), base64 encoded
+// []byte, comma separated slices ("a,b,c"), maps ("k1=v1,k2=v2") or implement encoding.TextUnmarshaler.
+// Slices of structs are read by index: TEST_LIST_0_NAME, TEST_LIST_1_NAME...
+func UnmarshalConfigFromEnv(ctx context.Context, prefix string, config any, opts ...func(*UnmarshalConfigOptions)) error {
+	options := UnmarshalConfigOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	found := map[string]bool{}
 	_, err := unmarshalConfig(prefix, reflect.ValueOf(config), func(key string) (*string, error) {
 		v, ok := os.LookupEnv(key)
 		if !ok {
 			return nil, nil
 		}
+		found[key] = true
 		return &v, nil
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	if options.sources != nil {
+		for _, field := range Fields(prefix, config) {
+			if found[field.EnvVar] {
+				options.sources[field.Path] = "env:" + field.EnvVar
+			}
+		}
+	}
+	return nil
 }
 
 func unmarshalConfig(prefix string, v reflect.Value, lookup func(string) (*string, error)) (didUnmarshal bool, err error) {
-	if v.Kind() == reflect.Ptr && !v.IsNil() {
-		if env, err := lookup(prefix); err != nil {
+	if v.Kind() == reflect.Ptr && !v.IsNil() && isLeaf(v.Type().Elem()) {
+		env, err := lookup(prefix)
+		if err != nil {
 			return false, err
-		} else if env != nil {
-			switch dest := v.Interface().(type) {
-			case *bool:
-				switch *env {
-				case "false":
-					*dest = false
-				case "true":
-					*dest = true
-				default:
-					return false, fmt.Errorf("boolean config must be \"true\" or \"false\"")
-				}
-			case *int:
-				n, err := strconv.Atoi(*env)
-				if err != nil {
-					return false, fmt.Errorf("invalid value for integer config")
-				}
-				*dest = n
-			case *string:
-				*dest = *env
-			case *[]byte:
-				buf, err := base64.StdEncoding.DecodeString(*env)
-				if err != nil {
-					return false, fmt.Errorf("byte slice configs must be base64 encoded")
-				}
-				*dest = buf
-			case *[]int:
-				parts := strings.Split(*env, ",")
-				intParts := make([]int, len(parts))
-				for i, part := range parts {
-					intParts[i], err = strconv.Atoi(strings.TrimSpace(part))
-					if err != nil {
-						return false, fmt.Errorf("invalid value for integer config")
-					}
-				}
-				*dest = intParts
-			case *[]string:
-				parts := strings.Split(*env, ",")
-				for i, part := range parts {
-					parts[i] = strings.TrimSpace(part)
-				}
-				*dest = parts
-			default:
-				return false, fmt.Errorf("unsupported environment config type %T", v.Elem().Interface())
-			}
-			return true, nil
+		} else if env == nil {
+			return false, nil
 		}
+		if err := setValue(prefix, *env, v.Elem()); err != nil {
+			return false, err
+		}
+		return true, nil
 	}
 
 	if v.Kind() == reflect.Ptr {
@@ -113,6 +95,9 @@ func unmarshalConfig(prefix string, v reflect.Value, lookup func(string) (*strin
 	if v.Kind() == reflect.Struct {
 		t := v.Type()
 		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
 			didUnmarshalField, err := unmarshalConfig(prefix+"_"+strings.ToUpper(t.Field(i).Name), v.Field(i).Addr(), lookup)
 			if err != nil {
 				return false, err
@@ -123,5 +108,145 @@ func unmarshalConfig(prefix string, v reflect.Value, lookup func(string) (*strin
 		}
 	}
 
+	// slices of structs are read element by element: PREFIX_0_FIELD, PREFIX_1_FIELD...
+	// Elements already in the slice are overridden, new elements are appended until an index is not found.
+	if v.Kind() == reflect.Slice {
+		elems := reflect.MakeSlice(v.Type(), 0, v.Len())
+		for i := 0; ; i++ {
+			elem := reflect.New(v.Type().Elem())
+			if i < v.Len() {
+				elem.Elem().Set(v.Index(i))
+			}
+			didUnmarshalElem, err := unmarshalConfig(prefix+"_"+strconv.Itoa(i), elem, lookup)
+			if err != nil {
+				return false, err
+			}
+			if i >= v.Len() && !didUnmarshalElem {
+				break
+			}
+			if didUnmarshalElem {
+				didUnmarshal = true
+			}
+			elems = reflect.Append(elems, elem.Elem())
+		}
+		if didUnmarshal {
+			v.Set(elems)
+		}
+	}
+
 	return didUnmarshal, nil
 }
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// isLeaf returns true if values of type t are read from a single environment variable.
+// Pointers, structs and slices of structs are walked instead, unless they implement encoding.TextUnmarshaler.
+func isLeaf(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Struct:
+		return false
+	case reflect.Slice:
+		return isLeaf(t.Elem()) && t.Elem().Kind() != reflect.Ptr
+	default:
+		return true
+	}
+}
+
+// setValue parses env into v. name is the environment variable env was read from, it is used in errors.
+func setValue(name, env string, v reflect.Value) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(env)); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			return fmt.Errorf("invalid duration for %s, expected a value like \"30s\": %w", name, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		switch env {
+		case "false":
+			v.SetBool(false)
+		case "true":
+			v.SetBool(true)
+		default:
+			return fmt.Errorf("boolean config %s must be \"true\" or \"false\"", name)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(env), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value for integer config %s: %w", name, err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(env), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value for unsigned integer config %s: %w", name, err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(env), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value for float config %s: %w", name, err)
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		v.SetString(env)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf, err := base64.StdEncoding.DecodeString(env)
+			if err != nil {
+				return fmt.Errorf("byte slice config %s must be base64 encoded", name)
+			}
+			v.SetBytes(buf)
+			return nil
+		}
+		parts := strings.Split(env, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(name, strings.TrimSpace(part), slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		// maps are formatted as key1=value1,key2=value2
+		m := reflect.MakeMap(v.Type())
+		for _, part := range strings.Split(env, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			rawKey, rawValue, ok := strings.Cut(part, "=")
+			if !ok {
+				return fmt.Errorf("invalid value for map config %s, expected key=value pairs separated by commas", name)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setValue(name, strings.TrimSpace(rawKey), key); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(name, strings.TrimSpace(rawValue), value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported environment config type %s for %s", v.Type(), name)
+	}
+	return nil
+}
@@ -1,42 +1,326 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v2"
 )
 
+// DefaultFilePath is the config file read when no file path is given.
+const DefaultFilePath = "config.yml"
+
+// IncludeKey is the top level key listing the files a config file includes. Paths are relative to the including
+// file and can be globs. Included files are read before the including file, which overrides them.
+const IncludeKey = "Include"
+
+// WithFilePath adds a config file. Config files are merged in the order they are given, later files override
+// earlier ones.
 func WithFilePath(filePath string) func(*UnmarshalConfigOptions) {
 	return func(options *UnmarshalConfigOptions) {
-		options.filePath = filePath
+		options.filePaths = append(options.filePaths, filePath)
+	}
+}
+
+// WithDropInDir merges every config file of dir, in lexical order, after the files given with WithFilePath.
+func WithDropInDir(dir string) func(*UnmarshalConfigOptions) {
+	return func(options *UnmarshalConfigOptions) {
+		options.dropInDir = dir
 	}
 }
 
-// UnmarshalConfigFromFile populates config with values from a yaml file.
+// UnmarshalConfigFromFile populates config with values from config files.
 // It will by default look for a file named config.yml in the current working directory.
-// You can override this by passing the WithFilePath option.
+// You can override this by passing the WithFilePath and WithDropInDir options.
+//
+// Files can be yaml, json or toml, depending on their extension. Their content is deep merged: objects are
+// merged key by key while lists and values of later files replace those of earlier files.
 func UnmarshalConfigFromFile(config any, opts ...func(*UnmarshalConfigOptions)) error {
 	options := UnmarshalConfigOptions{}
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	if options.filePath != "" {
-		f, err := os.Open(options.filePath)
+	layers, err := loadLayers(options)
+	if err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		return nil
+	}
+
+	merged := map[string]any{}
+	for _, layer := range layers {
+		// strict decoding is done file by file, so that errors point to the right file
+		if options.strict {
+			if err := decodeLayer(layer.content, reflect.New(reflect.TypeOf(config).Elem()).Interface(), true); err != nil {
+				return fmt.Errorf("failed to decode config file %s: %w", layer.path, err)
+			}
+		}
+		merged = mergeMaps(merged, layer.content)
+	}
+	if err := decodeLayer(merged, config, false); err != nil {
+		return fmt.Errorf("failed to decode config files %s: %w", strings.Join(layerPaths(layers), ", "), err)
+	}
+
+	if options.sources != nil {
+		for _, layer := range layers {
+			recordFileSources(options.sources, "", layer.content, "file:"+layer.path)
+		}
+	}
+
+	return nil
+}
+
+// ConfigFiles returns the config files read with opts, in the order they are merged.
+func ConfigFiles(opts ...func(*UnmarshalConfigOptions)) ([]string, error) {
+	options := UnmarshalConfigOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	layers, err := loadLayers(options)
+	if err != nil {
+		return nil, err
+	}
+	return layerPaths(layers), nil
+}
+
+// layer is the decoded content of a config file.
+type layer struct {
+	path    string
+	content map[string]any
+}
+
+func layerPaths(layers []layer) []string {
+	ret := make([]string, len(layers))
+	for i, layer := range layers {
+		ret[i] = layer.path
+	}
+	return ret
+}
+
+func loadLayers(options UnmarshalConfigOptions) ([]layer, error) {
+	filePaths := options.filePaths
+	if len(filePaths) == 0 {
+		// the default file is optional, and still the base of the drop-in directory when no file is given
+		if _, err := os.Stat(DefaultFilePath); err == nil {
+			filePaths = []string{DefaultFilePath}
+		}
+	}
+
+	if options.dropInDir != "" {
+		entries, err := os.ReadDir(options.dropInDir)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to read config directory: %w", err)
 		}
-		defer f.Close()
-		if err := yaml.NewDecoder(f).Decode(config); err != nil {
-			return fmt.Errorf("failed to decode config: %w", err)
+		// entries are sorted by name
+		for _, entry := range entries {
+			if !entry.IsDir() && isConfigFile(entry.Name()) {
+				filePaths = append(filePaths, filepath.Join(options.dropInDir, entry.Name()))
+			}
 		}
-	} else if f, err := os.Open("config.yml"); err == nil {
-		defer f.Close()
-		if err := yaml.NewDecoder(f).Decode(config); err != nil {
-			return fmt.Errorf("error decoding config: %w", err)
+	}
+
+	ret := []layer{}
+	for _, filePath := range filePaths {
+		layers, err := loadLayer(filePath, nil, options.strict)
+		if err != nil {
+			return nil, err
 		}
+		ret = append(ret, layers...)
 	}
+	return ret, nil
+}
 
-	return nil
+// loadLayer reads the file at filePath, preceded by the files it includes. including lists the files being
+// read, to detect include cycles.
+func loadLayer(filePath string, including []string, strict bool) ([]layer, error) {
+	for _, path := range including {
+		if path == filePath {
+			return nil, fmt.Errorf("config file %s includes itself through %s", filePath, strings.Join(including, " -> "))
+		}
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeFile(filePath, content, strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config file %s: %w", filePath, err)
+	}
+
+	ret := []layer{}
+	includes, err := includedFiles(filePath, decoded[IncludeKey])
+	if err != nil {
+		return nil, err
+	}
+	delete(decoded, IncludeKey)
+	for _, include := range includes {
+		layers, err := loadLayer(include, append(including, filePath), strict)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, layers...)
+	}
+	return append(ret, layer{path: filePath, content: decoded}), nil
+}
+
+// includedFiles returns the files listed by the include directive of the file at filePath.
+func includedFiles(filePath string, include any) ([]string, error) {
+	var patterns []string
+	switch include := include.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		patterns = []string{include}
+	case []any:
+		for _, pattern := range include {
+			s, ok := pattern.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s in config file %s: expected a path or a list of paths", IncludeKey, filePath)
+			}
+			patterns = append(patterns, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s in config file %s: expected a path or a list of paths", IncludeKey, filePath)
+	}
+
+	ret := []string{}
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(filePath), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in config file %s: %w", IncludeKey, filePath, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("config file %s includes %s: %w", filePath, pattern, os.ErrNotExist)
+		}
+		sort.Strings(matches)
+		ret = append(ret, matches...)
+	}
+	return ret, nil
+}
+
+func isConfigFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml", ".json", ".toml":
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeFile decodes a yaml, json or toml document depending on the extension of filePath.
+// Duplicate yaml keys are rejected if strict is true.
+func decodeFile(filePath string, content []byte, strict bool) (map[string]any, error) {
+	var raw any
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		m := map[string]any{}
+		if err := toml.Unmarshal(content, &m); err != nil {
+			return nil, err
+		}
+		raw = m
+	default:
+		decode := yaml.Unmarshal
+		if strict {
+			decode = yaml.UnmarshalStrict
+		}
+		if err := decode(content, &raw); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw == nil {
+		return map[string]any{}, nil
+	}
+	m, ok := normalize(raw).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object at the top level")
+	}
+	return m, nil
+}
+
+// normalize converts the values decoded from yaml, json and toml to the same types. Maps and slices are copied.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalize(value)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[key] = normalize(value)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, value := range v {
+			s[i] = normalize(value)
+		}
+		return s
+	case []map[string]any:
+		// toml arrays of tables
+		s := make([]any, len(v))
+		for i, value := range v {
+			s[i] = normalize(value)
+		}
+		return s
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// mergeMaps merges src into dst. Nested maps are merged, other values of src replace those of dst.
+// src is not modified, its values are copied.
+func mergeMaps(dst, src map[string]any) map[string]any {
+	for key, value := range src {
+		srcMap, srcOk := value.(map[string]any)
+		dstMap, dstOk := dst[key].(map[string]any)
+		if srcOk && dstOk {
+			dst[key] = mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = normalize(value)
+	}
+	return dst
+}
+
+// decodeLayer decodes merged config file content into config.
+func decodeLayer(content map[string]any, config any, strict bool) error {
+	buf, err := yaml.Marshal(content)
+	if err != nil {
+		return err
+	}
+	decode := yaml.Unmarshal
+	if strict {
+		decode = yaml.UnmarshalStrict
+	}
+	return decode(buf, config)
 }
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type LayeredConfig struct {
+	Name    string            `yaml:"Name"`
+	Timeout int               `yaml:"Timeout"`
+	Tags    []string          `yaml:"Tags"`
+	Labels  map[string]string `yaml:"Labels"`
+	Sub     LayeredSub        `yaml:"Sub"`
+}
+
+type LayeredSub struct {
+	Foo string  `yaml:"Foo"`
+	Bar float64 `yaml:"Bar"`
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestUnmarshalConfigFromFile_Layers(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"common.yml":             "Timeout: 5\nLabels:\n  team: storage\n",
+		"config.yml":             "Include: common.yml\nName: base\nTags: [a, b]\nLabels:\n  env: dev\nSub:\n  Foo: foo\n  Bar: 1\n",
+		"config.prod.json":       `{"Labels": {"env": "prod"}, "Tags": ["c"], "Sub": {"Bar": 2.5}}`,
+		"conf.d/10-name.toml":    "Name = \"toml\"\n[Sub]\nFoo = \"from-toml\"\n",
+		"conf.d/20-timeout.yaml": "Timeout: 30\n",
+		"conf.d/README.md":       "not a config file",
+	})
+
+	config := LayeredConfig{}
+	sources := Sources{}
+	opts := []func(*UnmarshalConfigOptions){
+		WithFilePath(filepath.Join(dir, "config.yml")),
+		WithFilePath(filepath.Join(dir, "config.prod.json")),
+		WithDropInDir(filepath.Join(dir, "conf.d")),
+		WithSources(sources),
+		WithStrict(),
+	}
+	require.NoError(t, UnmarshalConfigFromFile(&config, opts...))
+	assert.Equal(t, LayeredConfig{
+		Name:    "toml",
+		Timeout: 30,
+		Tags:    []string{"c"},
+		Labels:  map[string]string{"team": "storage", "env": "prod"},
+		Sub:     LayeredSub{Foo: "from-toml", Bar: 2.5},
+	}, config)
+	assert.Equal(t, "file:"+filepath.Join(dir, "common.yml"), sources.Get("Labels.team"))
+	assert.Equal(t, "file:"+filepath.Join(dir, "config.prod.json"), sources.Get("Labels.env"))
+	assert.Equal(t, "file:"+filepath.Join(dir, "conf.d", "20-timeout.yaml"), sources.Get("Timeout"))
+
+	files, err := ConfigFiles(opts...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "common.yml"),
+		filepath.Join(dir, "config.yml"),
+		filepath.Join(dir, "config.prod.json"),
+		filepath.Join(dir, "conf.d", "10-name.toml"),
+		filepath.Join(dir, "conf.d", "20-timeout.yaml"),
+	}, files)
+}
+
+func TestUnmarshalConfigFromFile_DefaultFileWithDropInDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		DefaultFilePath:         "Name: base\nTimeout: 5\n",
+		"conf.d/20-timeout.yml": "Timeout: 30\n",
+	})
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	config := LayeredConfig{}
+	require.NoError(t, UnmarshalConfigFromFile(&config, WithDropInDir("conf.d")))
+	assert.Equal(t, LayeredConfig{Name: "base", Timeout: 30}, config)
+}
+
+func TestUnmarshalConfigFromFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yml":       "Include: b.yml\n",
+		"b.yml":       "Include: [a.yml]\n",
+		"missing.yml": "Include: nope.yml\n",
+		"unknown.yml": "Nme: typo\n",
+		"list.yml":    "- Name\n",
+	})
+
+	for name, tc := range map[string]struct {
+		file   string
+		strict bool
+		err    string
+	}{
+		"Cycle":       {file: "a.yml", err: "includes itself"},
+		"Missing":     {file: "missing.yml", err: "nope.yml"},
+		"Strict":      {file: "unknown.yml", strict: true, err: "unknown.yml"},
+		"NotAnObject": {file: "list.yml", err: "list.yml"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts := []func(*UnmarshalConfigOptions){WithFilePath(filepath.Join(dir, tc.file))}
+			if tc.strict {
+				opts = append(opts, WithStrict())
+			}
+			assert.ErrorContains(t, UnmarshalConfigFromFile(&LayeredConfig{}, opts...), tc.err)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type OverrideKind string
+
+const (
+	// BucketOverride matches requests to a bucket with the exact same name.
+	BucketOverride OverrideKind = "bucket"
+	// UserAgentOverride matches requests whose user agent contains the override value.
+	UserAgentOverride OverrideKind = "useragent"
+)
+
+// Override is a temporary log level for requests matching a bucket or a user agent.
+type Override struct {
+	Kind      OverrideKind
+	Value     string
+	Level     zapcore.Level
+	ExpiresAt time.Time
+}
+
+// Overrides holds temporary log level overrides. Overrides expire automatically.
+type Overrides struct {
+	lock      sync.Mutex
+	overrides map[OverrideKind]map[string]Override
+	now       func() time.Time
+}
+
+func NewOverrides() *Overrides {
+	return &Overrides{
+		overrides: map[OverrideKind]map[string]Override{},
+		now:       time.Now,
+	}
+}
+
+// Set adds or replaces the override for kind and value, it expires after ttl.
+func (o *Overrides) Set(kind OverrideKind, value string, level zapcore.Level, ttl time.Duration) Override {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	override := Override{
+		Kind:      kind,
+		Value:     value,
+		Level:     level,
+		ExpiresAt: o.now().Add(ttl),
+	}
+	if o.overrides[kind] == nil {
+		o.overrides[kind] = map[string]Override{}
+	}
+	o.overrides[kind][value] = override
+	return override
+}
+
+// Delete removes the override for kind and value, if any.
+func (o *Overrides) Delete(kind OverrideKind, value string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.overrides[kind], value)
+}
+
+// Lookup returns the level override matching value for the given kind.
+// When several user agent overrides match, the most verbose one wins.
+func (o *Overrides) Lookup(kind OverrideKind, value string) (zapcore.Level, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.removeExpired()
+
+	overrides := o.overrides[kind]
+	if len(overrides) == 0 || value == "" {
+		return zapcore.InvalidLevel, false
+	}
+
+	switch kind {
+	case UserAgentOverride:
+		found := false
+		level := zapcore.InvalidLevel
+		for _, override := range overrides {
+			if strings.Contains(value, override.Value) && (!found || override.Level < level) {
+				found = true
+				level = override.Level
+			}
+		}
+		return level, found
+	default:
+		override, ok := overrides[value]
+		return override.Level, ok
+	}
+}
+
+// List returns every override that has not expired yet.
+func (o *Overrides) List() []Override {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.removeExpired()
+
+	ret := []Override{}
+	for _, overrides := range o.overrides {
+		for _, override := range overrides {
+			ret = append(ret, override)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		return ret[i].Value < ret[j].Value
+	})
+	return ret
+}
+
+// removeExpired removes expired overrides. o.lock must be held.
+func (o *Overrides) removeExpired() {
+	now := o.now()
+	for _, overrides := range o.overrides {
+		for value, override := range overrides {
+			if !now.Before(override.ExpiresAt) {
+				delete(overrides, value)
+			}
+		}
+	}
+}
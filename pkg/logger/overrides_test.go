@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestOverrides_Lookup(t *testing.T) {
+	now := time.Now()
+	overrides := NewOverrides()
+	overrides.now = func() time.Time { return now }
+
+	overrides.Set(BucketOverride, "my-bucket", zapcore.DebugLevel, time.Minute)
+	overrides.Set(UserAgentOverride, "spark", zapcore.InfoLevel, time.Minute)
+	overrides.Set(UserAgentOverride, "Hadoop", zapcore.DebugLevel, 2*time.Minute)
+
+	level, ok := overrides.Lookup(BucketOverride, "my-bucket")
+	assert.True(t, ok)
+	assert.Equal(t, zapcore.DebugLevel, level)
+
+	_, ok = overrides.Lookup(BucketOverride, "my-bucket-2")
+	assert.False(t, ok)
+
+	level, ok = overrides.Lookup(UserAgentOverride, "Hadoop 3.3.4, aws-sdk-java/1.12.262 spark")
+	assert.True(t, ok)
+	assert.Equal(t, zapcore.DebugLevel, level)
+
+	now = now.Add(90 * time.Second)
+	_, ok = overrides.Lookup(BucketOverride, "my-bucket")
+	assert.False(t, ok)
+	require.Len(t, overrides.List(), 1)
+	assert.Equal(t, "Hadoop", overrides.List()[0].Value)
+
+	overrides.Delete(UserAgentOverride, "Hadoop")
+	assert.Empty(t, overrides.List())
+}
+
+func TestLogger_WithLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	logger.Debug("dropped")
+	debugLogger := WithLevel(logger.With(zap.String("foo", "bar")), zapcore.DebugLevel)
+	assert.Equal(t, zapcore.DebugLevel, debugLogger.Level())
+	debugLogger.Debug("kept")
+	WithLevel(debugLogger, zapcore.ErrorLevel).Info("dropped")
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "kept", entries[0].Message)
+	assert.Equal(t, "bar", entries[0].ContextMap()["foo"])
+}
@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets, in seconds, suited to proxied S3 request latencies.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry holds a set of metrics and renders them in the prometheus text exposition format.
+type Registry struct {
+	lock    sync.RWMutex
+	metrics []metric
+	names   map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: map[string]metric{},
+	}
+}
+
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+func (r *Registry) register(m metric) metric {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if existing, ok := r.names[m.name()]; ok {
+		return existing
+	}
+	r.names[m.name()] = m
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// Counter registers a counter with the given label names. Registering the same name twice returns the
+// existing counter.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return r.register(&Counter{vec: newVec(name, help, "counter", labels)}).(*Counter)
+}
+
+// Gauge registers a gauge with the given label names. Registering the same name twice returns the
+// existing gauge.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return r.register(&Gauge{vec: newVec(name, help, "gauge", labels)}).(*Gauge)
+}
+
+// Histogram registers a histogram with the given buckets and label names. Registering the same name
+// twice returns the existing histogram.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return r.register(&Histogram{vec: newVec(name, help, "histogram", labels), buckets: sorted}).(*Histogram)
+}
+
+// WriteText writes every registered metric to w in the prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) {
+	r.lock.RLock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.lock.RUnlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler returns an http.Handler serving the registry in the prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+type vec struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+
+	lock   sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newVec(name, help, kind string, labels []string) vec {
+	return vec{
+		metricName: name,
+		help:       help,
+		kind:       kind,
+		labels:     labels,
+		series:     map[string]*series{},
+	}
+}
+
+func (v *vec) name() string {
+	return v.metricName
+}
+
+// get returns the series for labelValues, creating it if needed. v.lock must be held.
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.metricName, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sortedSeries returns a snapshot of every series ordered by label values. v.lock must be held.
+func (v *vec) sortedSeries() []series {
+	ret := make([]series, 0, len(v.series))
+	for _, s := range v.series {
+		cp := *s
+		cp.counts = append([]uint64(nil), s.counts...)
+		ret = append(ret, cp)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return strings.Join(ret[i].labelValues, "\xff") < strings.Join(ret[j].labelValues, "\xff")
+	})
+	return ret
+}
+
+func (v *vec) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.metricName, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.metricName, v.kind)
+}
+
+func formatLabels(names, values []string, extra ...string) string {
+	parts := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		parts = append(parts, name+"="+quoteLabelValue(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+"="+quoteLabelValue(extra[i+1]))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// labelValueReplacer escapes label values as in the prometheus text exposition format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabelValue(value string) string {
+	return `"` + labelValueReplacer.Replace(value) + `"`
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	vec
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.get(labelValues).value += delta
+}
+
+// Value returns the current value of the counter for the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.get(labelValues).value
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.writeHeader(w)
+	for _, s := range c.sortedSeries() {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, formatLabels(c.labels, s.labelValues), formatFloat(s.value))
+	}
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	vec
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.get(labelValues).value = value
+}
+
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.get(labelValues).value += delta
+}
+
+// Value returns the current value of the gauge for the given label values.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.get(labelValues).value
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.writeHeader(w)
+	for _, s := range g.sortedSeries() {
+		fmt.Fprintf(w, "%s%s %s\n", g.metricName, formatLabels(g.labels, s.labelValues), formatFloat(s.value))
+	}
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	vec
+	buckets []float64
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	s := h.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.writeHeader(w)
+	for _, s := range h.sortedSeries() {
+		for i, upper := range h.buckets {
+			var count uint64
+			if s.counts != nil {
+				count = s.counts[i]
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(h.labels, s.labelValues, "le", formatFloat(upper)), count)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(h.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, formatLabels(h.labels, s.labelValues), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, formatLabels(h.labels, s.labelValues), s.count)
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_WriteText(t *testing.T) {
+	registry := NewRegistry()
+
+	counter := registry.Counter("test_requests_total", "Total requests.", "code")
+	counter.Inc("200")
+	counter.Inc("200")
+	counter.Inc("500")
+	counter.Inc("caf\u00e9 \"a\\b\"\n")
+	assert.Same(t, counter, registry.Counter("test_requests_total", "Total requests.", "code"))
+
+	gauge := registry.Gauge("test_inflight", "In flight requests.")
+	gauge.Add(3)
+	gauge.Add(-1)
+
+	histogram := registry.Histogram("test_duration_seconds", "Request duration.", []float64{1, 0.1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(2)
+
+	var buf bytes.Buffer
+	registry.WriteText(&buf)
+	assert.Equal(t, `# HELP test_requests_total Total requests.
+# TYPE test_requests_total counter
+test_requests_total{code="200"} 2
+test_requests_total{code="500"} 1
+test_requests_total{code="café \"a\\b\"\n"} 1
+# HELP test_inflight In flight requests.
+# TYPE test_inflight gauge
+test_inflight 2
+# HELP test_duration_seconds Request duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 2.55
+test_duration_seconds_count 3
+`, buf.String())
+}
@@ -0,0 +1,105 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+var ErrCaptureInProgress = errors.New("a cpu profile capture is already in progress")
+
+// Profiler captures cpu profiles on demand.
+// Captured profiles are in the pprof format expected by go's profile guided optimization,
+// they can be used directly as default.pgo.
+type Profiler struct {
+	dir string
+
+	lock    sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	stopped bool
+}
+
+// NewProfiler creates a Profiler saving captured profiles in dir.
+// If dir is empty, captured profiles are not saved to disk.
+func NewProfiler(dir string) *Profiler {
+	return &Profiler{
+		dir: dir,
+	}
+}
+
+// CaptureCPU records a cpu profile for duration, or until ctx is done or the profiler is stopped,
+// and writes it to w. It returns the path of the saved profile, if any.
+func (p *Profiler) CaptureCPU(ctx context.Context, duration time.Duration, w io.Writer) (string, error) {
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return "", fmt.Errorf("profiler is stopped")
+	}
+	if p.stop != nil {
+		p.lock.Unlock()
+		return "", ErrCaptureInProgress
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		p.lock.Unlock()
+		return "", fmt.Errorf("could not start cpu profile: %w", err)
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	p.stop, p.done = stop, done
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		p.stop, p.done = nil, nil
+		p.lock.Unlock()
+		close(done)
+	}()
+
+	timer := time.NewTimer(duration)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-stop:
+	}
+	timer.Stop()
+	pprof.StopCPUProfile()
+
+	path := ""
+	if p.dir != "" {
+		path = filepath.Join(p.dir, fmt.Sprintf("cpu-%s.pgo", time.Now().UTC().Format("20060102T150405Z")))
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return "", fmt.Errorf("could not save cpu profile: %w", err)
+		}
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// Stop ends any capture in progress, flushing it, and prevents new captures.
+func (p *Profiler) Stop() {
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return
+	}
+	p.stopped = true
+	stop, done := p.stop, p.done
+	p.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,50 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiler_CaptureCPU(t *testing.T) {
+	ctx := context.Background()
+	profiler := NewProfiler(t.TempDir())
+
+	var buf bytes.Buffer
+	path, err := profiler.CaptureCPU(ctx, 100*time.Millisecond, &buf)
+	require.NoError(t, err)
+	assert.NotEmpty(t, buf.Bytes())
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), saved)
+}
+
+func TestProfiler_Stop(t *testing.T) {
+	ctx := context.Background()
+	profiler := NewProfiler("")
+
+	errs := make(chan error, 1)
+	var buf bytes.Buffer
+	go func() {
+		_, err := profiler.CaptureCPU(ctx, time.Hour, &buf)
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, err := profiler.CaptureCPU(ctx, time.Millisecond, &bytes.Buffer{})
+		return err == ErrCaptureInProgress
+	}, time.Second, 10*time.Millisecond)
+
+	profiler.Stop()
+	require.NoError(t, <-errs)
+	assert.NotEmpty(t, buf.Bytes())
+
+	_, err := profiler.CaptureCPU(ctx, time.Millisecond, &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -1,24 +1,122 @@
 package shutdown
 
 import (
+	"context"
+	"sync"
 	"sync/atomic"
+	"time"
+)
+
+// Phase is a step of the graceful shutdown. Phases run in order.
+type Phase int
+
+const (
+	// PhaseReadiness stops advertising readiness. Terminating is set when it starts.
+	PhaseReadiness Phase = iota
+	// PhasePreStop waits for the preStop delay, so that load balancers stop sending new requests.
+	PhasePreStop
+	// PhaseDrain waits for in-flight requests to complete, up to a deadline.
+	PhaseDrain
+	// PhaseFlush flushes logs, profiles and releases resources.
+	PhaseFlush
+
+	numPhases
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseReadiness:
+		return "readiness"
+	case PhasePreStop:
+		return "preStop"
+	case PhaseDrain:
+		return "drain"
+	case PhaseFlush:
+		return "flush"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	phaseFuncs   [numPhases][]func(ctx context.Context)
+	preShutDown  = func() {}
+	gracePeriod  = 30 * time.Second
+	preStopDelay = time.Duration(0)
+	lock         sync.Mutex
+	once         sync.Once
 )
 
-var shutdownFuncs = make([]func(), 0)
-var preShutDown = func() {}
 var Terminating = &atomic.Bool{}
 
+// OnShutdown registers f to run during PhaseFlush.
+// Flush functions run in reverse registration order, like deferred calls.
 func OnShutdown(f func()) {
-	shutdownFuncs = append(shutdownFuncs, f)
+	OnPhase(PhaseFlush, func(ctx context.Context) {
+		f()
+	})
+}
+
+// OnPhase registers f to run during phase. ctx is done when the grace period is over.
+func OnPhase(phase Phase, f func(ctx context.Context)) {
+	lock.Lock()
+	defer lock.Unlock()
+	phaseFuncs[phase] = append(phaseFuncs[phase], f)
 }
 
 func WithPreShutdown(f func()) {
+	lock.Lock()
+	defer lock.Unlock()
 	preShutDown = f
 }
+
+// WithGracePeriod bounds the duration of the whole shutdown, 30s by default.
+func WithGracePeriod(d time.Duration) {
+	lock.Lock()
+	defer lock.Unlock()
+	gracePeriod = d
+}
+
+// WithPreStopDelay sets how long PhasePreStop waits before draining requests, 0 by default.
+func WithPreStopDelay(d time.Duration) {
+	lock.Lock()
+	defer lock.Unlock()
+	preStopDelay = d
+}
+
+// Shutdown runs every shutdown phase in order, within the grace period.
+// It only runs once, concurrent calls wait for the first one to complete.
 func Shutdown() {
-	Terminating.Store(true)
-	preShutDown()
-	for _, f := range shutdownFuncs {
-		f()
-	}
+	once.Do(func() {
+		lock.Lock()
+		funcs := phaseFuncs
+		pre := preShutDown
+		grace := gracePeriod
+		delay := preStopDelay
+		lock.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+
+		Terminating.Store(true)
+		pre()
+		for phase := PhaseReadiness; phase < numPhases; phase++ {
+			if phase == PhasePreStop && delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+				}
+			}
+
+			if phase == PhaseFlush {
+				for i := len(funcs[phase]) - 1; i >= 0; i-- {
+					funcs[phase][i](ctx)
+				}
+				continue
+			}
+			for _, f := range funcs[phase] {
+				f(ctx)
+			}
+		}
+	})
 }
@@ -0,0 +1,37 @@
+package shutdown
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown_Phases(t *testing.T) {
+	WithGracePeriod(time.Second)
+	WithPreStopDelay(10 * time.Millisecond)
+
+	calls := []string{}
+	OnShutdown(func() { calls = append(calls, "flush 1") })
+	OnShutdown(func() { calls = append(calls, "flush 2") })
+	OnPhase(PhaseDrain, func(ctx context.Context) {
+		assert.True(t, Terminating.Load())
+		calls = append(calls, "drain")
+		// the drain is bounded by the grace period
+		<-ctx.Done()
+	})
+	OnPhase(PhaseReadiness, func(ctx context.Context) { calls = append(calls, "readiness") })
+
+	begin := time.Now()
+	done := make(chan struct{})
+	go func() {
+		Shutdown()
+		close(done)
+	}()
+	Shutdown()
+	<-done
+
+	assert.InDelta(t, time.Second, time.Since(begin), float64(500*time.Millisecond))
+	assert.Equal(t, []string{"readiness", "drain", "flush 2", "flush 1"}, calls)
+}
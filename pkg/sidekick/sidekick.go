@@ -0,0 +1,191 @@
+// Package sidekick serves the sidekick proxy from an http.Handler, so that go services can mount it in-process.
+//
+//	handler, err := sidekick.New(sidekick.WithBackend(sidekick.AWS), sidekick.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	defer handler.Close()
+//	mux.Handle("/", handler)
+//
+// Handlers do not share any state: credentials, clients and metrics belong to each handler.
+package sidekick
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/project-n-oss/sidekick/api"
+	"github.com/project-n-oss/sidekick/app"
+	"go.uber.org/zap"
+)
+
+// Backend is the cloud platform requests are proxied to.
+type Backend string
+
+const (
+	AWS Backend = Backend(app.AwsCloudPlatform)
+	GCP Backend = Backend(app.GcpCloudPlatform)
+)
+
+// CrunchPolicy decides what happens to requests for source files that have a crunched version.
+type CrunchPolicy int
+
+const (
+	// CrunchPolicyLock fails requests for source files that have a crunched version with a 409 SidekickCrunchLocked error.
+	CrunchPolicyLock CrunchPolicy = iota
+	// CrunchPolicyIgnore serves source files even if they have a crunched version.
+	CrunchPolicyIgnore
+)
+
+type Options struct {
+	appConfig    app.Config
+	backend      Backend
+	logger       *zap.Logger
+	credentials  aws.CredentialsProvider
+	httpClient   *http.Client
+	crunchPolicy *CrunchPolicy
+}
+
+// WithConfig sets the app configuration, e.g. per bucket credentials. The other options override it.
+func WithConfig(cfg app.Config) func(*Options) {
+	return func(o *Options) {
+		o.appConfig = cfg
+	}
+}
+
+// WithBackend sets the cloud platform requests are proxied to, AWS by default.
+func WithBackend(backend Backend) func(*Options) {
+	return func(o *Options) {
+		o.backend = backend
+	}
+}
+
+// WithLogger sets the logger of the handler, nothing is logged by default.
+func WithLogger(logger *zap.Logger) func(*Options) {
+	return func(o *Options) {
+		o.logger = logger
+	}
+}
+
+// WithCredentialsProvider signs upstream aws requests with provider instead of the default credential chain.
+func WithCredentialsProvider(provider aws.CredentialsProvider) func(*Options) {
+	return func(o *Options) {
+		o.credentials = provider
+	}
+}
+
+// WithHTTPClient sends upstream requests with client, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) func(*Options) {
+	return func(o *Options) {
+		o.httpClient = client
+	}
+}
+
+// WithCrunchPolicy sets what happens to requests for source files that have a crunched version,
+// CrunchPolicyLock by default.
+func WithCrunchPolicy(policy CrunchPolicy) func(*Options) {
+	return func(o *Options) {
+		o.crunchPolicy = &policy
+	}
+}
+
+// Handler proxies requests to the backend. It must be closed to release its resources.
+type Handler struct {
+	handler http.Handler
+	app     *app.App
+	api     *api.Api
+	cancel  context.CancelFunc
+	once    sync.Once
+	err     error
+}
+
+// New creates a sidekick handler.
+func New(opts ...func(*Options)) (*Handler, error) {
+	options := Options{
+		backend: AWS,
+		logger:  zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	cfg := options.appConfig
+	if cfg.CloudPlatform == "" || options.backend != AWS {
+		cfg.CloudPlatform = string(options.backend)
+	}
+	if options.crunchPolicy != nil {
+		cfg.NoCrunchErr = *options.crunchPolicy == CrunchPolicyIgnore
+	}
+	appOpts := []func(*app.Options){}
+	if options.credentials != nil {
+		appOpts = append(appOpts, app.WithAwsCredentials(options.credentials))
+	}
+	if options.httpClient != nil {
+		appOpts = append(appOpts, app.WithUpstreamHttpClient(options.httpClient))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sidekickApp, err := app.New(ctx, options.logger, zap.NewAtomicLevelAt(options.logger.Level()), cfg, appOpts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	sidekickApi, err := api.New(ctx, api.Config{}, sidekickApp)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Handler{
+		handler: sidekickApi.CreateHandler(),
+		app:     sidekickApp,
+		api:     sidekickApi,
+		cancel:  cancel,
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// AdminHandler returns the handler of the admin api: health, readiness, metrics etc.
+func (h *Handler) AdminHandler() http.Handler {
+	return h.api.CreateAdminHandler()
+}
+
+// Shutdown rejects new requests, then closes the handler once the requests being served complete,
+// or when ctx is done.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	if err := h.api.Close(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for h.api.InFlight() > 0 {
+		select {
+		case <-ctx.Done():
+			h.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return h.Close()
+}
+
+// Close releases the resources of the handler and stops its background refreshes. Requests served after
+// Close fail with a 503 ServiceUnavailable error. Close can be called several times.
+func (h *Handler) Close() error {
+	h.once.Do(func() {
+		h.cancel()
+		if err := h.api.Close(context.Background()); err != nil {
+			h.err = err
+			return
+		}
+		h.err = h.app.Close(context.Background())
+	})
+	return h.err
+}
@@ -0,0 +1,95 @@
+package sidekick
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sidekickAws "github.com/project-n-oss/sidekick/app/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:7075/my-bucket/my-key", nil)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=client_key/20231010/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=abc")
+	return req
+}
+
+func TestSidekick_New(t *testing.T) {
+	var upstreamRequests atomic.Int32
+	client := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			upstreamRequests.Add(1)
+			assert.Equal(t, "s3.us-east-1.amazonaws.com", req.URL.Host)
+			assert.Contains(t, req.Header.Get("Authorization"), "Credential=embedded_key/")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/plain"}},
+				Body:       io.NopCloser(strings.NewReader("hello")),
+			}, nil
+		}),
+	}
+	handler, err := New(
+		WithBackend(AWS),
+		WithCredentialsProvider(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "embedded_key", SecretAccessKey: "embedded_secret"}, nil
+		})),
+		WithHTTPClient(client),
+		WithCrunchPolicy(CrunchPolicyIgnore),
+	)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, int32(1), upstreamRequests.Load())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, handler.Shutdown(ctx))
+	require.NoError(t, handler.Close())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest())
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var body struct {
+		Code string `xml:"Code"`
+	}
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "ServiceUnavailable", body.Code)
+	assert.Equal(t, int32(1), upstreamRequests.Load())
+}
+
+func TestSidekick_Isolation(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "foobar_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "foobar_secret")
+	first, err := New(WithCrunchPolicy(CrunchPolicyIgnore))
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := New(WithCrunchPolicy(CrunchPolicyIgnore))
+	require.NoError(t, err)
+	defer second.Close()
+
+	require.NoError(t, first.app.AwsRegistry().CheckCredentials(context.Background(), sidekickAws.DefaultCredentialsSource, "us-east-1"))
+	assert.Equal(t, 1, first.app.AwsRegistry().Stats().Credentials)
+	assert.Equal(t, 0, second.app.AwsRegistry().Stats().Credentials)
+
+	_, err = New(WithBackend("AZURE"))
+	assert.Error(t, err)
+}